@@ -3,14 +3,34 @@ package config
 import (
 	"bytes"
 	_ "embed"
+	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+	"github.com/mtvy/blog-api-gateway/internal/broker"
+	"github.com/mtvy/blog-api-gateway/internal/cors"
+	"github.com/mtvy/blog-api-gateway/internal/feature"
+	"github.com/mtvy/blog-api-gateway/internal/gateway"
+	"github.com/mtvy/blog-api-gateway/internal/health"
+	"github.com/mtvy/blog-api-gateway/internal/idempotency"
+	"github.com/mtvy/blog-api-gateway/internal/lifecycle"
 	"github.com/mtvy/blog-api-gateway/internal/logger"
+	"github.com/mtvy/blog-api-gateway/internal/maintenance"
+	"github.com/mtvy/blog-api-gateway/internal/metrics"
+	"github.com/mtvy/blog-api-gateway/internal/outbox"
+	"github.com/mtvy/blog-api-gateway/internal/ratelimit"
+	"github.com/mtvy/blog-api-gateway/internal/tracing"
+	"github.com/mtvy/blog-api-gateway/internal/webhook"
 	"github.com/pkg/errors"
+	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 )
 
@@ -18,57 +38,226 @@ import (
 var defaultYamlFile []byte
 
 type Config struct {
-	HTTP struct {
-		Port int
+	HTTP   HTTPConfig
+	CORS   cors.Config
+	Admin  AdminConfig
+	Log    logger.Config
+	Stream struct {
+		ReplaySize   int           `mapstructure:"replay_size" validate:"gte=0"`
+		ClientBuffer int           `mapstructure:"client_buffer" validate:"gt=0"`
+		Heartbeat    time.Duration `mapstructure:"heartbeat" validate:"gt=0"`
 	}
-	Log logger.Config
+	Webhook     webhook.Config
+	NATS        broker.Config
+	Outbox      outbox.Config
+	Gateway     gateway.Config
+	RateLimit   ratelimit.Config `mapstructure:"rate_limit"`
+	Auth        auth.Config
+	Idempotency idempotency.Config
+	Metrics     metrics.Config
+	Tracing     tracing.Config
+	Health      health.Config
+	Maintenance maintenance.Config
+	Features    feature.Config
+	Shutdown    lifecycle.Config
 }
 
-func Parse() (*Config, error) {
-	return parse(defaultYamlFile, "BLOG_APIGATEWAY")
+// HTTPConfig: нулевые таймауты отключают ограничение, нулевой BodyLimit
+// означает ограничение Fiber по умолчанию (4 МБ). WriteTimeout ограничивает
+// и потоковые ответы, поэтому по умолчанию не задан. TrustedProxies - адреса
+// и подсети прокси, которым доверяется X-Forwarded-For.
+type HTTPConfig struct {
+	Port           int           `mapstructure:"port" validate:"port"`
+	RequestTimeout time.Duration `mapstructure:"request_timeout" validate:"gte=0"`
+	ReadTimeout    time.Duration `mapstructure:"read_timeout" validate:"gte=0"`
+	WriteTimeout   time.Duration `mapstructure:"write_timeout" validate:"gte=0"`
+	IdleTimeout    time.Duration `mapstructure:"idle_timeout" validate:"gte=0"`
+	BodyLimit      int           `mapstructure:"body_limit" validate:"gte=0"`
+	TLS            TLSConfig     `mapstructure:"tls"`
+	TrustedProxies []string      `mapstructure:"trusted_proxies" validate:"dive,ip|cidr"`
+}
+
+// TLSConfig: при заданных сертификате и ключе сервер принимает только HTTPS.
+type TLSConfig struct {
+	CertFile string `mapstructure:"cert_file" validate:"required_with=KeyFile,omitempty,file"`
+	KeyFile  string `mapstructure:"key_file" validate:"required_with=CertFile,omitempty,file"`
+}
+
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// AdminConfig: служебный API (уровень журнала, pprof, сведения о сборке,
+// конфигурация, кэш, режим обслуживания) на отдельном порту с собственной
+// учетной записью HTTP Basic.
+type AdminConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Port     int    `mapstructure:"port" validate:"port"`
+	Username string `mapstructure:"username" validate:"required_if=Enabled true"`
+	Password string `mapstructure:"password" validate:"required_if=Enabled true,omitempty,min=16" secret:"true"`
+}
+
+const (
+	EnvPrefix = "BLOG_APIGATEWAY"
+
+	// EnvConfigFile - переменная окружения с путем к файлу конфигурации.
+	EnvConfigFile = EnvPrefix + "_CONFIG"
+
+	defaultConfigName = "config"
+	dotEnvFile        = ".env"
+)
+
+// Loader читает конфигурацию: значения по умолчанию, файл конфигурации,
+// переменные окружения и файл .env, каждый следующий источник
+// переопределяет предыдущий, а .env не переопределяет переменные окружения.
+// Нулевые FS и LookupEnv заменяются файловой системой и окружением процесса.
+// Args - аргументы командной строки без имени программы.
+type Loader struct {
+	FS        afero.Fs
+	LookupEnv func(key string) (string, bool)
+	Args      []string
+}
+
+// Load читает конфигурацию и проверяет ее целиком, сообщая обо всех
+// ошибках сразу.
+func (l Loader) Load() (*Config, error) {
+	cfg, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
 func (c *Config) GetHTTPEndpoint() string {
 	return fmt.Sprintf(":%d", c.HTTP.Port)
 }
 
-func parse(defaultYamlFile []byte, envPrefix string) (*Config, error) {
-	viper.SetConfigName("config")
-	viper.AddConfigPath(".")
-	viper.SetConfigType("yaml")
+// ConfigFile возвращает путь к файлу конфигурации: из флага --config, из
+// BLOG_APIGATEWAY_CONFIG или файл config с любым расширением, которое
+// поддерживает viper (config.yml, config.yaml, config.json и т.д.), в
+// рабочем каталоге, если он есть. Пустой путь означает, что используются
+// только значения по умолчанию.
+func (l Loader) ConfigFile() (string, error) {
+	flags := flag.NewFlagSet("blog-api-gateway", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	file := flags.String("config", "", "path to the configuration file")
+	if err := flags.Parse(l.Args); err != nil {
+		return "", errors.Wrap(err, "parse flags")
+	}
+	if *file != "" {
+		return *file, nil
+	}
+	if file, ok := l.lookupEnv()(EnvConfigFile); ok && file != "" {
+		return file, nil
+	}
 
-	if err := viper.ReadConfig(bytes.NewBuffer(defaultYamlFile)); err != nil {
+	finder := viper.New()
+	finder.SetFs(l.fs())
+	finder.SetConfigName(defaultConfigName)
+	finder.AddConfigPath(".")
+	// ошибки разбора найденного файла сообщает load
+	err := finder.ReadInConfig()
+	var notFound viper.ConfigFileNotFoundError
+	if errors.As(err, &notFound) {
+		return "", nil
+	}
+	return finder.ConfigFileUsed(), nil
+}
+
+func (l Loader) load() (*Config, error) {
+	v := viper.New()
+	v.SetFs(l.fs())
+	v.SetConfigType("yaml")
+
+	if err := v.ReadConfig(bytes.NewReader(defaultYamlFile)); err != nil {
 		return nil, errors.Wrap(err, "reading default settings")
 	}
 
-	if err := viper.MergeInConfig(); err != nil {
-		var configFileNotFoundError viper.ConfigFileNotFoundError
-		if !errors.As(err, &configFileNotFoundError) {
-			return nil, err
+	file, err := l.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	if file != "" {
+		// формат определяется по расширению, файл без известного расширения
+		// читается как YAML
+		if ext := strings.TrimPrefix(filepath.Ext(file), "."); slices.Contains(viper.SupportedExts, ext) {
+			v.SetConfigType(ext)
 		}
-	} else {
-		if file := viper.ConfigFileUsed(); file != "" {
-			slog.Info(fmt.Sprintf("configuration file: %s", file))
+		data, err := afero.ReadFile(l.fs(), file)
+		if err != nil {
+			return nil, errors.Wrap(err, "read configuration file")
 		}
+		if err := v.MergeConfig(bytes.NewReader(data)); err != nil {
+			return nil, errors.Wrapf(err, "parse configuration file %s", file)
+		}
+		slog.Info(fmt.Sprintf("configuration file: %s", file))
 	}
 
-	if _, err := os.Stat("./.env"); !errors.Is(err, os.ErrNotExist) {
-		if err = godotenv.Load("./.env"); err != nil {
-			return nil, errors.Wrap(err, "read .env file")
-		} else {
-			slog.Info("using .env file")
+	lookupEnv, err := l.withDotEnv()
+	if err != nil {
+		return nil, err
+	}
+	// ключи, не известные viper, из окружения не читаются, как и при
+	// AutomaticEnv
+	replacer := strings.NewReplacer(".", "_")
+	for _, key := range v.AllKeys() {
+		name := EnvPrefix + "_" + strings.ToUpper(replacer.Replace(key))
+		if value, ok := lookupEnv(name); ok {
+			v.Set(key, value)
 		}
 	}
 
-	viper.SetEnvPrefix(envPrefix)
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	viper.AutomaticEnv()
-
 	cfg := &Config{}
 
-	if err := viper.Unmarshal(cfg); err != nil {
+	if err := v.Unmarshal(cfg); err != nil {
 		return nil, errors.Wrap(err, "parse settings")
 	}
 
 	return cfg, nil
 }
+
+// withDotEnv дополняет окружение переменными из .env, если файл есть.
+// Окружение процесса не меняется.
+func (l Loader) withDotEnv() (func(string) (string, bool), error) {
+	lookupEnv := l.lookupEnv()
+
+	f, err := l.fs().Open(dotEnvFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return lookupEnv, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read .env file")
+	}
+	defer f.Close()
+
+	dotEnv, err := godotenv.Parse(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "read .env file")
+	}
+	slog.Info("using .env file")
+
+	return func(key string) (string, bool) {
+		if value, ok := lookupEnv(key); ok {
+			return value, true
+		}
+		value, ok := dotEnv[key]
+		return value, ok
+	}, nil
+}
+
+func (l Loader) fs() afero.Fs {
+	if l.FS == nil {
+		return afero.NewOsFs()
+	}
+	return l.FS
+}
+
+func (l Loader) lookupEnv() func(string) (string, bool) {
+	if l.LookupEnv == nil {
+		return os.LookupEnv
+	}
+	return l.LookupEnv
+}
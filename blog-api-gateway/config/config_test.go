@@ -0,0 +1,219 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/feature"
+	"github.com/spf13/afero"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLoader читает конфигурацию из files и env, не обращаясь к файловой
+// системе и окружению процесса. Файлы с относительным путем доступны и по
+// абсолютному: viper ищет файл конфигурации по абсолютному пути рабочего
+// каталога.
+func testLoader(t *testing.T, files, env map[string]string, args ...string) Loader {
+	fs := afero.NewMemMapFs()
+	for name, content := range files {
+		require.NoError(t, afero.WriteFile(fs, name, []byte(content), 0o644))
+		require.NoError(t, afero.WriteFile(fs, absPath(t, name), []byte(content), 0o644))
+	}
+	return Loader{
+		FS: fs,
+		LookupEnv: func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		},
+		Args: args,
+	}
+}
+
+func absPath(t *testing.T, name string) string {
+	path, err := filepath.Abs(name)
+	require.NoError(t, err)
+	return path
+}
+
+func defaults(t *testing.T) *Config {
+	cfg, err := testLoader(t, nil, nil).load()
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestLoader_Load(t *testing.T) {
+	testCases := []struct {
+		name  string
+		files map[string]string
+		env   map[string]string
+		args  []string
+		check func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 8080, cfg.HTTP.Port)
+				assert.Equal(t, 10*time.Second, cfg.HTTP.RequestTimeout)
+				assert.Equal(t, "info", cfg.Log.Level)
+				assert.Equal(t, []string{"/metrics", "/swagger", "/healthz", "/readyz"}, cfg.Log.Access.ExcludePaths)
+				assert.Equal(t, 30*time.Second, cfg.Shutdown.Timeout)
+				assert.Equal(t, []feature.Flag{{Name: "posts_ws", Enabled: true}}, cfg.Features.Flags)
+			},
+		},
+		{
+			name: "file_overrides_defaults",
+			files: map[string]string{
+				"config.yml": "http:\n  port: 9000\nlog:\n  level: debug\n",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 9000, cfg.HTTP.Port)
+				assert.Equal(t, "debug", cfg.Log.Level)
+				assert.Equal(t, 10*time.Second, cfg.HTTP.RequestTimeout, "unset keys keep defaults")
+			},
+		},
+		{
+			name: "other_format",
+			files: map[string]string{
+				"config.toml": "[http]\nport = 9004\n",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 9004, cfg.HTTP.Port)
+			},
+		},
+		{
+			name: "config_flag",
+			files: map[string]string{
+				"config.yml":           "http:\n  port: 9000\n",
+				"/etc/blog/config.yml": "http:\n  port: 9001\n",
+			},
+			env:  map[string]string{"BLOG_APIGATEWAY_CONFIG": "config.yml"},
+			args: []string{"--config", "/etc/blog/config.yml"},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 9001, cfg.HTTP.Port)
+			},
+		},
+		{
+			name: "config_env",
+			files: map[string]string{
+				"config.yml":           "http:\n  port: 9000\n",
+				"/etc/blog/config.yml": "http:\n  port: 9001\n",
+			},
+			env: map[string]string{"BLOG_APIGATEWAY_CONFIG": "/etc/blog/config.yml"},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 9001, cfg.HTTP.Port)
+			},
+		},
+		{
+			name: "env_overrides_file",
+			files: map[string]string{
+				"config.yml": "http:\n  port: 9000\n",
+			},
+			env: map[string]string{
+				"BLOG_APIGATEWAY_HTTP_PORT":              "9002",
+				"BLOG_APIGATEWAY_HTTP_TRUSTED_PROXIES":   "10.0.0.1,10.1.0.0/16",
+				"BLOG_APIGATEWAY_RATE_LIMIT_REDIS_ADDR":  "redis:6379",
+				"BLOG_APIGATEWAY_SHUTDOWN_DRAIN_PERIOD":  "1s",
+				"BLOG_APIGATEWAY_LOG_ACCESS_SAMPLE_RATE": "0.5",
+				"BLOG_APIGATEWAY_UNKNOWN_KEY":            "ignored",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 9002, cfg.HTTP.Port)
+				assert.Equal(t, []string{"10.0.0.1", "10.1.0.0/16"}, cfg.HTTP.TrustedProxies)
+				assert.Equal(t, "redis:6379", cfg.RateLimit.Redis.Addr)
+				assert.Equal(t, time.Second, cfg.Shutdown.DrainPeriod)
+				assert.Equal(t, 0.5, cfg.Log.Access.SampleRate)
+			},
+		},
+		{
+			name: "dot_env",
+			files: map[string]string{
+				".env": "BLOG_APIGATEWAY_HTTP_PORT=9003\nBLOG_APIGATEWAY_LOG_LEVEL=warn\n",
+			},
+			env: map[string]string{"BLOG_APIGATEWAY_LOG_LEVEL": "error"},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 9003, cfg.HTTP.Port)
+				assert.Equal(t, "error", cfg.Log.Level, ".env does not override the environment")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := testLoader(t, tc.files, tc.env, tc.args...).Load()
+			require.NoError(t, err)
+			tc.check(t, cfg)
+		})
+	}
+}
+
+func TestLoader_Errors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		files map[string]string
+		env   map[string]string
+		args  []string
+		want  string
+	}{
+		{
+			name: "missing_config_flag_file",
+			args: []string{"--config", "missing.yml"},
+			want: "read configuration file",
+		},
+		{
+			name: "missing_config_env_file",
+			env:  map[string]string{"BLOG_APIGATEWAY_CONFIG": "missing.yml"},
+			want: "read configuration file",
+		},
+		{
+			name: "unknown_flag",
+			args: []string{"--port", "80"},
+			want: "parse flags",
+		},
+		{
+			name:  "malformed_file",
+			files: map[string]string{"config.yml": "http: [port"},
+			want:  "parse configuration file " + absPath(t, "config.yml"),
+		},
+		{
+			name:  "malformed_dot_env",
+			files: map[string]string{".env": "BLOG_APIGATEWAY_HTTP_PORT='9000"},
+			want:  "read .env file",
+		},
+		{
+			name: "invalid_value",
+			env:  map[string]string{"BLOG_APIGATEWAY_HTTP_PORT": "70000"},
+			want: "http.port(port)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := testLoader(t, tc.files, tc.env, tc.args...).Load()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.want)
+		})
+	}
+}
+
+func TestLoader_ConfigFile(t *testing.T) {
+	file, err := testLoader(t, nil, nil).ConfigFile()
+	require.NoError(t, err)
+	assert.Empty(t, file, "defaults only")
+
+	for _, name := range []string{"config.yml", "config.yaml", "config.json"} {
+		file, err = testLoader(t, map[string]string{name: ""}, nil).ConfigFile()
+		require.NoError(t, err)
+		assert.Equal(t, absPath(t, name), file)
+	}
+
+	file, err = testLoader(t, map[string]string{"settings.yml": ""}, nil).ConfigFile()
+	require.NoError(t, err)
+	assert.Empty(t, file, "other names are not looked up")
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// Redacted возвращает конфигурацию деревом ключей конфигурации для вывода.
+// Непустые значения полей с тегом secret:"true" скрываются, длительности
+// записываются строкой, как в config.yml.
+func (c *Config) Redacted() map[string]any {
+	return redact(reflect.ValueOf(*c)).(map[string]any)
+}
+
+func redact(v reflect.Value) any {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		m := make(map[string]any, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Tag.Get("secret") == "true" && !v.Field(i).IsZero() {
+				m[configKey(field)] = redacted
+				continue
+			}
+			m[configKey(field)] = redact(v.Field(i))
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		s := make([]any, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s = append(s, redact(v.Index(i)))
+		}
+		return s
+	case reflect.Map:
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = redact(iter.Value())
+		}
+		return m
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redact(v.Elem())
+	default:
+		return v.Interface()
+	}
+}
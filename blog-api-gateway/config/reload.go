@@ -0,0 +1,140 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+// settleDelay - пауза после изменения файла перед чтением, за которую
+// завершается запись.
+const settleDelay = 100 * time.Millisecond
+
+// Merge возвращает конфигурацию, которую можно применить к работающему
+// сервису: current с перезагружаемыми настройками из next (log.level,
+// cors, rate_limit.enabled, rate_limit.rules, gateway.routes, maintenance,
+// features). restart - секции, прочие изменения в которых требуют
+// перезапуска и не применяются.
+func Merge(current, next *Config) (applied *Config, restart []string) {
+	merged := *current
+	merged.Log.Level = next.Log.Level
+	merged.CORS = next.CORS
+	merged.RateLimit.Enabled = next.RateLimit.Enabled
+	merged.RateLimit.Rules = next.RateLimit.Rules
+	merged.Gateway.Routes = next.Gateway.Routes
+	merged.Maintenance = next.Maintenance
+	merged.Features = next.Features
+
+	a, n := reflect.ValueOf(merged), reflect.ValueOf(*next)
+	for i := 0; i < a.NumField(); i++ {
+		if !reflect.DeepEqual(a.Field(i).Interface(), n.Field(i).Interface()) {
+			restart = append(restart, configKey(a.Type().Field(i)))
+		}
+	}
+	return &merged, restart
+}
+
+// Watch вызывает reload при изменении файла конфигурации file и по SIGHUP,
+// пока не отменен ctx или не вызвана stop. Пустой file не отслеживается.
+// Вызовы reload не пересекаются, изменения, пришедшие во время перезагрузки,
+// объединяются в один вызов. stop прекращает наблюдение за файлом и
+// дожидается завершения текущей перезагрузки.
+func Watch(ctx context.Context, file string, reload func()) (stop func(), err error) {
+	changed := make(chan struct{}, 1)
+	var watcher *fsnotify.Watcher
+	if file != "" {
+		watcher, err = watchFile(file, changed)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("watching configuration file: " + file)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+				// файл часто записывается в несколько приемов: сначала
+				// обрезается, затем заполняется
+				time.Sleep(settleDelay)
+				select {
+				case <-changed:
+				default:
+				}
+				slog.Info("configuration file changed, reloading")
+			case <-hup:
+				slog.Info("SIGHUP received, reloading configuration")
+			}
+			reload()
+		}
+	}()
+
+	return func() {
+		cancel()
+		if watcher != nil {
+			_ = watcher.Close()
+		}
+		<-done
+	}, nil
+}
+
+// watchFile сообщает в changed об изменениях file. Наблюдается каталог
+// файла, а не сам файл: редакторы и Kubernetes заменяют файл новым, и
+// наблюдение за прежним прекратилось бы. Замена цели символической ссылки
+// тоже считается изменением.
+func watchFile(file string, changed chan<- struct{}) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "create file watcher")
+	}
+	file = filepath.Clean(file)
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		_ = watcher.Close()
+		return nil, errors.Wrapf(err, "watch %s", filepath.Dir(file))
+	}
+
+	target, _ := filepath.EvalSymlinks(file)
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				current, _ := filepath.EvalSymlinks(file)
+				written := filepath.Clean(event.Name) == file && event.Has(fsnotify.Write|fsnotify.Create)
+				if !written && (current == "" || current == target) {
+					continue
+				}
+				target = current
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("configuration file watcher", slog.Any("error", err))
+			}
+		}
+	}()
+	return watcher, nil
+}
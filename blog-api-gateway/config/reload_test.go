@@ -0,0 +1,97 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/feature"
+	"github.com/mtvy/blog-api-gateway/internal/gateway"
+	"github.com/mtvy/blog-api-gateway/internal/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	current := defaults(t)
+
+	testCases := []struct {
+		name    string
+		modify  func(cfg *Config)
+		restart []string
+	}{
+		{
+			name:   "unchanged",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name: "reloadable",
+			modify: func(cfg *Config) {
+				cfg.Log.Level = "debug"
+				cfg.CORS.Enabled = true
+				cfg.CORS.AllowOrigins = []string{"https://blog.example.com"}
+				cfg.RateLimit.Enabled = !cfg.RateLimit.Enabled
+				cfg.RateLimit.Rules = []ratelimit.Rule{{Prefix: "/posts", Limit: 1, Period: time.Second}}
+				cfg.Gateway.Routes = []gateway.Route{{Prefix: "/comments"}}
+				cfg.Maintenance.Enabled = true
+				cfg.Maintenance.RetryAfter = time.Minute
+				cfg.Features.Flags = []feature.Flag{{Name: "search", Enabled: true}}
+			},
+		},
+		{
+			name: "restart_required",
+			modify: func(cfg *Config) {
+				cfg.Log.Level = "debug"
+				cfg.HTTP.Port++
+				cfg.RateLimit.Store = ratelimit.StoreRedis
+				cfg.Stream.ClientBuffer++
+			},
+			restart: []string{"http", "stream", "rate_limit"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next := *current
+			tc.modify(&next)
+
+			applied, restart := Merge(current, &next)
+			assert.Equal(t, tc.restart, restart)
+			assert.Equal(t, next.Log.Level, applied.Log.Level)
+			assert.Equal(t, next.CORS, applied.CORS)
+			assert.Equal(t, next.RateLimit.Rules, applied.RateLimit.Rules)
+			assert.Equal(t, next.Gateway.Routes, applied.Gateway.Routes)
+			assert.Equal(t, next.Maintenance, applied.Maintenance)
+			assert.Equal(t, next.Features, applied.Features)
+			assert.Equal(t, current.HTTP, applied.HTTP)
+			assert.Equal(t, current.RateLimit.Store, applied.RateLimit.Store)
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(file, []byte("log:\n  level: info\n"), 0o600))
+
+	reloads := make(chan struct{}, 10)
+	stop, err := Watch(context.Background(), file, func() { reloads <- struct{}{} })
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(file, []byte("log:\n  level: debug\n"), 0o600))
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("change is not reloaded")
+	}
+
+	stop()
+	require.NoError(t, os.WriteFile(file, []byte("log:\n  level: warn\n"), 0o600))
+	select {
+	case <-reloads:
+		t.Fatal("change is reloaded after stop")
+	case <-time.After(2 * settleDelay):
+	}
+}
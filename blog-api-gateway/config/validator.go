@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"log/slog"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -27,15 +28,49 @@ func validateNatsUrls(fl validator.FieldLevel) bool {
 
 // Проверяет валидность порта (0-65535)
 func validatePort(fl validator.FieldLevel) bool {
-	port, err := strconv.Atoi(fl.Field().String())
-	if err != nil {
-		return false
+	var port int64
+	switch fl.Field().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		port = fl.Field().Int()
+	default:
+		p, err := strconv.Atoi(fl.Field().String())
+		if err != nil {
+			return false
+		}
+		port = int64(p)
 	}
 	return port >= 0 && port <= 65535
 }
 
+// Проверяет, что включенный служебный API не занимает порт API или метрик
+func validateAdminPort(sl validator.StructLevel) {
+	cfg := sl.Current().Interface().(Config)
+	if !cfg.Admin.Enabled {
+		return
+	}
+	if cfg.Admin.Port == cfg.HTTP.Port {
+		sl.ReportError(cfg.Admin.Port, "admin.port", "Port", "nefield", "http.port")
+	}
+	if cfg.Metrics.Enabled && cfg.Admin.Port == cfg.Metrics.Port {
+		sl.ReportError(cfg.Admin.Port, "admin.port", "Port", "nefield", "metrics.port")
+	}
+}
+
+// configKey возвращает ключ конфигурации поля: тег mapstructure или имя поля
+// в нижнем регистре, как его сопоставляет viper.
+func configKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// Validate проверяет cfg по тегам validate и возвращает все нарушения
+// сразу. Поля называются по ключам конфигурации, например http.port(port).
 func Validate(cfg interface{}) error {
 	v := validator.New()
+	v.RegisterTagNameFunc(configKey)
 
 	if err := v.RegisterValidation("log_level", validateLogLevel); err != nil {
 		return errors.Wrap(err, "register custom validation: log_level")
@@ -46,11 +81,22 @@ func Validate(cfg interface{}) error {
 	if err := v.RegisterValidation("port", validatePort); err != nil {
 		return errors.Wrap(err, "register custom validation: port")
 	}
+	v.RegisterStructValidation(validateAdminPort, Config{})
 
 	if err := v.Struct(cfg); err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return errors.Wrap(err, "validate configuration")
+		}
 		var messages []string
-		for _, e := range err.(validator.ValidationErrors) {
-			messages = append(messages, fmt.Sprintf("%s(%s)", e.StructNamespace(), e.Tag()))
+		for _, e := range validationErrors {
+			// пространство имен начинается с имени корневой структуры
+			_, field, _ := strings.Cut(e.Namespace(), ".")
+			rule := e.Tag()
+			if e.Param() != "" {
+				rule += "=" + e.Param()
+			}
+			messages = append(messages, fmt.Sprintf("%s(%s)", field, rule))
 		}
 		return errors.New("invalid configuration: " + strings.Join(messages, ", "))
 	}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/feature"
+	"github.com/mtvy/blog-api-gateway/internal/gateway"
+	"github.com/mtvy/blog-api-gateway/internal/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(cfg *Config)
+		want   []string
+	}{
+		{
+			name:   "defaults",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name: "all_errors_at_once",
+			modify: func(cfg *Config) {
+				cfg.HTTP.Port = 70000
+				cfg.Log.Level = "verbose"
+				cfg.Outbox.Sinks = []string{"kafka"}
+			},
+			want: []string{"http.port(port)", "log.level(log_level)", "outbox.sinks[0](oneof=log stream webhook nats)"},
+		},
+		{
+			name: "http",
+			modify: func(cfg *Config) {
+				cfg.HTTP.ReadTimeout = -time.Second
+				cfg.HTTP.TLS.CertFile = "config.go"
+				cfg.HTTP.TrustedProxies = []string{"10.0.0.0/8", "proxy"}
+			},
+			want: []string{"http.read_timeout(gte=0)", "http.tls.key_file(required_with=CertFile)", "http.trusted_proxies[1](ip|cidr)"},
+		},
+		{
+			name: "admin",
+			modify: func(cfg *Config) {
+				cfg.Admin.Enabled = true
+				cfg.Admin.Username = ""
+			},
+			want: []string{"admin.username(required_if=Enabled true)", "admin.password(required_if=Enabled true)"},
+		},
+		{
+			name: "admin_port_taken",
+			modify: func(cfg *Config) {
+				cfg.Admin.Enabled = true
+				cfg.Admin.Password = "0123456789abcdef"
+				cfg.Admin.Port = cfg.HTTP.Port
+				cfg.Metrics.Enabled = true
+				cfg.Metrics.Port = cfg.HTTP.Port
+			},
+			want: []string{"admin.port(nefield=http.port)", "admin.port(nefield=metrics.port)"},
+		},
+		{
+			name: "nested_lists",
+			modify: func(cfg *Config) {
+				cfg.Gateway.Routes = []gateway.Route{{Prefix: "comments"}}
+				cfg.RateLimit.Rules = append(cfg.RateLimit.Rules, ratelimit.Rule{Prefix: "/posts", Key: "user"})
+				cfg.Features.Flags = []feature.Flag{{Name: "search"}, {Name: "patch", Key: "ip"}}
+			},
+			want: []string{
+				"gateway.routes[0].prefix(startswith=/)",
+				"gateway.routes[0].upstreams(required)",
+				"rate_limit.rules[1].key(oneof=ip subject api_key)",
+				"rate_limit.rules[1].limit(gt=0)",
+				"features.flags[1].key(oneof=subject api_key)",
+			},
+		},
+		{
+			name: "duplicate_flags",
+			modify: func(cfg *Config) {
+				cfg.Features.Flags = []feature.Flag{{Name: "search"}, {Name: "search", Enabled: true}}
+			},
+			want: []string{"features.flags(unique=Name)"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := defaults(t)
+			tc.modify(cfg)
+
+			err := Validate(cfg)
+			if len(tc.want) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, want := range tc.want {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
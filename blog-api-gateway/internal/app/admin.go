@@ -0,0 +1,46 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/basicauth"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
+	"github.com/mtvy/blog-api-gateway/config"
+	"github.com/mtvy/blog-api-gateway/internal/handler"
+	"github.com/mtvy/blog-api-gateway/internal/logger"
+)
+
+// getAdminRouter создает служебный API. Он слушает отдельный порт и не
+// проходит через аутентификацию по ключам API и ограничение запросов
+// публичного API.
+func getAdminRouter(cfg config.AdminConfig, ops *handler.OpsHandle, features *handler.FeatureHandle, accessLog logger.AccessConfig) *fiber.App {
+	app := fiber.New(fiber.Config{
+		ErrorHandler:          errorHandler,
+		DisableStartupMessage: true,
+	})
+
+	app.Use(logger.Access(accessLog))
+	app.Use(basicauth.New(basicauth.Config{
+		Users: map[string]string{cfg.Username: cfg.Password},
+		Realm: "admin",
+		Unauthorized: func(c *fiber.Ctx) error {
+			c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="admin"`)
+			return fiber.NewError(http.StatusUnauthorized)
+		},
+	}))
+	app.Use(pprof.New())
+
+	app.Get("/log-level", ops.GetLogLevel)
+	app.Put("/log-level", ops.SetLogLevel)
+	app.Get("/build-info", ops.GetBuildInfo)
+	app.Get("/config", ops.GetConfig)
+	app.Post("/cache/purge", ops.PurgeCache)
+	app.Get("/maintenance", ops.GetMaintenance)
+	app.Put("/maintenance", ops.SetMaintenance)
+	app.Get("/flags", features.ListFlags)
+	app.Put("/flags/:name", features.SetFlag)
+	app.Delete("/flags/:name", features.DeleteFlag)
+
+	return app
+}
@@ -1,33 +1,299 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
 	"github.com/mtvy/blog-api-gateway/config"
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+	"github.com/mtvy/blog-api-gateway/internal/broker"
+	"github.com/mtvy/blog-api-gateway/internal/cors"
+	"github.com/mtvy/blog-api-gateway/internal/feature"
+	"github.com/mtvy/blog-api-gateway/internal/gateway"
 	"github.com/mtvy/blog-api-gateway/internal/handler"
+	"github.com/mtvy/blog-api-gateway/internal/health"
+	"github.com/mtvy/blog-api-gateway/internal/idempotency"
+	"github.com/mtvy/blog-api-gateway/internal/lifecycle"
 	"github.com/mtvy/blog-api-gateway/internal/logger"
+	"github.com/mtvy/blog-api-gateway/internal/maintenance"
+	"github.com/mtvy/blog-api-gateway/internal/metrics"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/internal/outbox"
+	"github.com/mtvy/blog-api-gateway/internal/ratelimit"
 	"github.com/mtvy/blog-api-gateway/internal/repository"
+	"github.com/mtvy/blog-api-gateway/internal/stream"
+	"github.com/mtvy/blog-api-gateway/internal/tracing"
 	"github.com/mtvy/blog-api-gateway/internal/usecase"
+	"github.com/mtvy/blog-api-gateway/internal/webhook"
 	"github.com/mtvy/blog-api-gateway/migrations"
 	"github.com/pkg/errors"
 )
 
-func Run() error {
-	cfg, err := config.Parse()
+// Run запускает сервис с конфигурацией loader и останавливает его по
+// SIGINT или SIGTERM.
+func Run(loader config.Loader) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := loader.Load()
 	if err != nil {
 		return errors.Wrap(err, "parse cfg")
 	}
 
 	logger.Register(cfg.Log)
 
+	lc := lifecycle.New()
+	// при ошибке запуска останавливаются уже запущенные компоненты; после
+	// штатной остановки вызов ничего не делает
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+		defer cancel()
+		_ = lc.Shutdown(ctx)
+	}()
+
+	shutdownTracing, err := tracing.Setup(cfg.Tracing)
+	if err != nil {
+		return errors.Wrap(err, "tracing")
+	}
+	lc.Register("tracing", shutdownTracing)
+
+	probes := health.New(cfg.Health)
+
 	repo := repository.NewPostProvider()
+	probes.Register("repository", 0, repo.Ping)
+	migrator := migrations.New(repo)
+	var migrated atomic.Bool
+	probes.Register("migrations", 0, func(context.Context) error {
+		if !migrated.Load() {
+			return errors.New("not applied")
+		}
+		return nil
+	})
+	hub := stream.NewHub(cfg.Stream.ReplaySize, cfg.Stream.ClientBuffer)
+
+	webhookRepo := repository.NewWebhookProvider(cfg.Webhook.LogSize)
+	dispatcher := webhook.New(cfg.Webhook, webhookRepo, webhookRepo)
+	dispatcher.Start()
+	lc.Register("webhook dispatcher", dispatcher.Stop)
+
+	sinks := map[string]outbox.Sink{
+		"log": outbox.LogSink,
+		"stream": outbox.SinkFunc(func(event models.PostEvent) error {
+			hub.Publish(event)
+			return nil
+		}),
+		"webhook": dispatcher,
+	}
+	if cfg.NATS.Enabled {
+		publisher, err := broker.NewNATSPublisher(cfg.NATS)
+		if err != nil {
+			return errors.Wrap(err, "nats publisher")
+		}
+		lc.RegisterCloser("nats publisher", publisher.Close)
+		sinks["nats"] = publisher
+		probes.RegisterOptional("nats", 0, publisher.Ping)
+	}
+
+	relaySinks := make(map[string]outbox.Sink, len(cfg.Outbox.Sinks))
+	for _, name := range cfg.Outbox.Sinks {
+		sink, ok := sinks[name]
+		if !ok {
+			return errors.Errorf("outbox sink %q is unknown or disabled", name)
+		}
+		relaySinks[name] = sink
+	}
+
+	relay := outbox.NewRelay(cfg.Outbox, repo, relaySinks)
+	relay.Start()
+	lc.RegisterFunc("outbox relay", relay.Stop)
+
+	gw, err := gateway.New(cfg.Gateway)
+	if err != nil {
+		return errors.Wrap(err, "gateway")
+	}
+	gw.Start()
+	lc.RegisterFunc("gateway health checks", gw.Stop)
+	// маршруты могут появиться при перезагрузке конфигурации
+	probes.RegisterOptional("gateway", 0, gw.Ping)
+	probes.Describe("gateway", func() any { return gw.Status() })
+
+	apiKeyUC := usecase.NewAPIKeyProvider(repository.NewAPIKeyProvider())
+	if cfg.Auth.BootstrapKey != "" {
+		if err := apiKeyUC.Bootstrap(ctx, cfg.Auth.BootstrapKey); err != nil {
+			return errors.Wrap(err, "bootstrap api key")
+		}
+	}
+
+	store, err := ratelimit.NewStore(cfg.RateLimit)
+	if err != nil {
+		return errors.Wrap(err, "rate limit store")
+	}
+	if closer, ok := store.(io.Closer); ok {
+		lc.RegisterCloser("rate limit store", closer.Close)
+	}
+	if pinger, ok := store.(interface{ Ping(context.Context) error }); ok {
+		probes.RegisterOptional("rate_limit_store", 0, pinger.Ping)
+	}
+	limiter, err := ratelimit.New(cfg.RateLimit, store)
+	if err != nil {
+		return errors.Wrap(err, "rate limiter")
+	}
+
+	features, err := feature.New(cfg.Features)
+	if err != nil {
+		return errors.Wrap(err, "feature flags")
+	}
+
+	corsRules, err := cors.New(cfg.CORS)
+	if err != nil {
+		return errors.Wrap(err, "cors")
+	}
+
 	uc := usecase.NewPostProvider(repo)
-	handle := handler.New(uc)
+	idem := idempotency.New(cfg.Idempotency)
+	mode := maintenance.New(cfg.Maintenance)
+	probes.Describe("maintenance", func() any { return mode.Enabled() })
+	h := handlers{
+		post:     handler.New(uc),
+		stream:   handler.NewStream(hub, cfg.Stream.Heartbeat),
+		webhook:  handler.NewWebhook(usecase.NewWebhookProvider(webhookRepo)),
+		outbox:   handler.NewOutbox(usecase.NewOutboxProvider(repo)),
+		upstream: handler.NewUpstream(gw),
+		migrate:  handler.NewMigration(migrator),
+		dataset:  handler.NewDataset(usecase.NewDatasetProvider(repo)),
+		gateway:  gw,
+		apiKey:   handler.NewAPIKey(apiKeyUC),
+		cors:     corsRules,
+		auth:     auth.New(cfg.Auth, apiKeyUC),
+		limiter:  limiter,
+		idem:     idem,
+		health:   probes,
+
+		maintenance: mode,
+		features:    features,
+
+		accessLog: cfg.Log.Access,
+		http:      cfg.HTTP,
+	}
+
+	// ошибка любого из серверов останавливает сервис
+	listenErr := make(chan error, 3)
+	if cfg.Metrics.Enabled {
+		h.metrics = newMetrics(repo, dispatcher, mode, gw)
+		if cfg.Metrics.Port == 0 || cfg.Metrics.Port == cfg.HTTP.Port {
+			h.metricsPath = cfg.Metrics.Path
+		} else {
+			metricsApp := fiber.New(fiber.Config{DisableStartupMessage: true})
+			metricsApp.Get(cfg.Metrics.Path, h.metrics.Handler())
+			go func() {
+				if err := metricsApp.Listen(fmt.Sprintf(":%d", cfg.Metrics.Port)); err != nil {
+					listenErr <- errors.Wrap(err, "metrics server listen")
+				}
+			}()
+			lc.Register("metrics server", metricsApp.ShutdownWithContext)
+		}
+	}
+
+	reload := &reloader{loader: loader, current: cfg, cors: corsRules, limiter: limiter, gateway: gw, maintenance: mode, features: features}
 
-	if err := migrations.Migrate(repo); err != nil {
+	if cfg.Admin.Enabled {
+		adminApp := getAdminRouter(cfg.Admin, handler.NewOps(reload, idem, mode), handler.NewFeature(features), cfg.Log.Access)
+		go func() {
+			if err := adminApp.Listen(fmt.Sprintf(":%d", cfg.Admin.Port)); err != nil {
+				listenErr <- errors.Wrap(err, "admin server listen")
+			}
+		}()
+		lc.Register("admin server", adminApp.ShutdownWithContext)
+	}
+
+	// сервер принимает пробы уже во время миграций, готовность наступает
+	// после их применения
+	router := getRouter(h)
+	go func() {
+		if cfg.HTTP.TLS.Enabled() {
+			listenErr <- errors.Wrap(router.ListenTLS(cfg.GetHTTPEndpoint(), cfg.HTTP.TLS.CertFile, cfg.HTTP.TLS.KeyFile), "server listen")
+			return
+		}
+		listenErr <- errors.Wrap(router.Listen(cfg.GetHTTPEndpoint()), "server listen")
+	}()
+	// потоки изменений постов не завершаются сами, поэтому закрываются до
+	// ожидания текущих запросов
+	lc.Register("http server", func(ctx context.Context) error {
+		hub.Close()
+		return router.ShutdownWithContext(ctx)
+	})
+
+	if err := migrator.Up(ctx); err != nil {
+		probes.Drain()
 		return errors.Wrap(err, "migrations")
 	}
+	migrated.Store(true)
 
-	if err := getRouter(handle).Listen(cfg.GetHTTPEndpoint()); err != nil {
-		return errors.Wrap(err, "server listen")
+	configFile, err := loader.ConfigFile()
+	if err != nil {
+		return errors.Wrap(err, "config file")
 	}
+	stopWatch, err := config.Watch(ctx, configFile, reload.reload)
+	if err != nil {
+		return errors.Wrap(err, "watch config")
+	}
+	lc.RegisterFunc("config watcher", stopWatch)
+
+	select {
+	case err := <-listenErr:
+		return err
+	case <-ctx.Done():
+	}
+	// повторный сигнал завершает процесс сразу
+	stop()
+
+	slog.Info("shutting down", slog.Duration("drain_period", cfg.Shutdown.DrainPeriod))
+	probes.Drain()
+	time.Sleep(cfg.Shutdown.DrainPeriod)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+	defer cancel()
+	if err := lc.Shutdown(shutdownCtx); err != nil {
+		return errors.Wrap(err, "shutdown")
+	}
+	slog.Info("shutdown complete")
 	return nil
 }
+
+func newMetrics(repo *repository.PostRepo, dispatcher *webhook.Dispatcher, mode *maintenance.Mode, gw *gateway.Gateway) *metrics.Metrics {
+	m := metrics.New()
+	m.Gauge("posts", "Number of stored posts.", func() float64 {
+		return float64(repo.CountPosts())
+	})
+	m.LabeledGauge("outbox_entries", "Outbox entries waiting for delivery by status.", "status", func() map[string]float64 {
+		values := map[string]float64{
+			string(models.OutboxPending): 0,
+			string(models.OutboxFailed):  0,
+		}
+		for status, count := range repo.CountOutbox() {
+			values[string(status)] = float64(count)
+		}
+		return values
+	})
+	m.Gauge("webhook_deliveries_pending", "Webhook deliveries queued or waiting for retry.", func() float64 {
+		return float64(dispatcher.Pending())
+	})
+	m.Gauge("maintenance_mode", "1 while maintenance mode rejects changes to posts.", func() float64 {
+		if mode.Enabled() {
+			return 1
+		}
+		return 0
+	})
+	gw.ObserveBreakers(func(prefix string, state gateway.BreakerState) {
+		m.SetBreakerState(prefix, string(state))
+	})
+	return m
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"log/slog"
+	"reflect"
+	"sync"
+
+	"github.com/mtvy/blog-api-gateway/config"
+	"github.com/mtvy/blog-api-gateway/internal/cors"
+	"github.com/mtvy/blog-api-gateway/internal/feature"
+	"github.com/mtvy/blog-api-gateway/internal/gateway"
+	"github.com/mtvy/blog-api-gateway/internal/logger"
+	"github.com/mtvy/blog-api-gateway/internal/maintenance"
+	"github.com/mtvy/blog-api-gateway/internal/ratelimit"
+)
+
+// reloader применяет к работающему сервису изменения конфигурации, не
+// требующие перезапуска. Конфигурация с ошибками отклоняется целиком.
+type reloader struct {
+	loader  config.Loader
+	cors    *cors.CORS
+	limiter *ratelimit.Limiter
+	gateway *gateway.Gateway
+	// maintenance переключается, только если maintenance.enabled в
+	// конфигурации изменился, чтобы не отменять переключение через
+	// служебный API.
+	maintenance *maintenance.Mode
+	// features заменяются, только если флаги в конфигурации изменились, по
+	// той же причине.
+	features *feature.Flags
+
+	mu      sync.Mutex
+	current *config.Config
+}
+
+// Redacted возвращает действующую конфигурацию со скрытыми секретами.
+func (r *reloader) Redacted() map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current.Redacted()
+}
+
+func (r *reloader) reload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := r.loader.Load()
+	if err != nil {
+		slog.Error("config reload rejected, keeping current configuration", slog.Any("error", err))
+		return
+	}
+
+	applied, restart := config.Merge(r.current, next)
+	if len(restart) > 0 {
+		slog.Warn("config changes require restart and were not applied", slog.Any("sections", restart))
+	}
+
+	if applied.Log.Level != r.current.Log.Level {
+		if err := logger.SetLevel(applied.Log.Level); err != nil {
+			slog.Error("reload log level", slog.Any("error", err))
+			applied.Log.Level = r.current.Log.Level
+		} else {
+			slog.Info("min log level set: " + logger.Level().String())
+		}
+	}
+	if err := r.cors.Update(applied.CORS); err != nil {
+		slog.Error("reload cors rules", slog.Any("error", err))
+		applied.CORS = r.current.CORS
+	}
+	if err := r.limiter.Update(applied.RateLimit); err != nil {
+		slog.Error("reload rate limits", slog.Any("error", err))
+		applied.RateLimit = r.current.RateLimit
+	}
+	if err := r.gateway.Update(applied.Gateway.Routes); err != nil {
+		slog.Error("reload gateway routes", slog.Any("error", err))
+		applied.Gateway = r.current.Gateway
+	}
+
+	if applied.Maintenance.Enabled != r.current.Maintenance.Enabled {
+		r.maintenance.Set(applied.Maintenance.Enabled)
+		slog.Warn("maintenance mode changed", slog.Bool("enabled", applied.Maintenance.Enabled))
+	}
+	r.maintenance.SetRetryAfter(applied.Maintenance.RetryAfter)
+	if !reflect.DeepEqual(applied.Features, r.current.Features) {
+		if err := r.features.Update(applied.Features); err != nil {
+			slog.Error("reload feature flags", slog.Any("error", err))
+			applied.Features = r.current.Features
+		}
+	}
+
+	r.current = applied
+	slog.Info("configuration reloaded")
+}
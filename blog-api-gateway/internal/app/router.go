@@ -1,40 +1,225 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"slices"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
 	"github.com/gofiber/swagger"
+	"github.com/mtvy/blog-api-gateway/config"
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+	"github.com/mtvy/blog-api-gateway/internal/cors"
+	"github.com/mtvy/blog-api-gateway/internal/deadline"
+	"github.com/mtvy/blog-api-gateway/internal/feature"
+	"github.com/mtvy/blog-api-gateway/internal/gateway"
 	"github.com/mtvy/blog-api-gateway/internal/handler"
+	"github.com/mtvy/blog-api-gateway/internal/health"
+	"github.com/mtvy/blog-api-gateway/internal/idempotency"
+	"github.com/mtvy/blog-api-gateway/internal/logger"
+	"github.com/mtvy/blog-api-gateway/internal/maintenance"
+	"github.com/mtvy/blog-api-gateway/internal/metrics"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/internal/ratelimit"
+	"github.com/mtvy/blog-api-gateway/internal/tracing"
+	"github.com/pkg/errors"
 )
 
-func getRouter(handle *handler.Handle) *fiber.App {
-	app := fiber.New(fiber.Config{
+// statusClientClosedRequest - нестандартный код nginx для запросов, отмененных
+// клиентом.
+const statusClientClosedRequest = 499
+
+// flagPostsWS включает поток изменений постов через WebSocket.
+const flagPostsWS = "posts_ws"
+
+type handlers struct {
+	post     *handler.Handle
+	stream   *handler.StreamHandle
+	webhook  *handler.WebhookHandle
+	outbox   *handler.OutboxHandle
+	upstream *handler.UpstreamHandle
+	migrate  *handler.MigrationHandle
+	dataset  *handler.DatasetHandle
+	gateway  *gateway.Gateway
+	apiKey   *handler.APIKeyHandle
+	cors     *cors.CORS
+	auth     *auth.Authenticator
+	limiter  *ratelimit.Limiter
+	idem     *idempotency.Cache
+	health   *health.Health
+	// maintenance запрещает изменение постов, пока включен режим
+	// обслуживания.
+	maintenance *maintenance.Mode
+	// features включают новые маршруты для части клиентов.
+	features *feature.Flags
+
+	accessLog logger.AccessConfig
+
+	http config.HTTPConfig
+
+	// metrics nil, если метрики отключены; metricsPath пуст, если они
+	// отдаются на отдельном порту.
+	metrics     *metrics.Metrics
+	metricsPath string
+}
+
+func getRouter(h handlers) *fiber.App {
+	cfg := fiber.Config{
 		ErrorHandler: errorHandler,
-	})
+		ReadTimeout:  h.http.ReadTimeout,
+		WriteTimeout: h.http.WriteTimeout,
+		IdleTimeout:  h.http.IdleTimeout,
+		BodyLimit:    h.http.BodyLimit,
+		// тела начальных данных и импорта больше BodyLimit читаются потоком,
+		// остальные ограничивает bufferBody
+		StreamRequestBody: true,
+	}
+	if len(h.http.TrustedProxies) > 0 {
+		cfg.EnableTrustedProxyCheck = true
+		cfg.TrustedProxies = h.http.TrustedProxies
+		cfg.ProxyHeader = fiber.HeaderXForwardedFor
+	}
+	app := fiber.New(cfg)
+
+	app.Use(logger.Access(h.accessLog))
+	app.Use(tracing.Handle)
+	if h.metrics != nil {
+		app.Use(h.metrics.Handle)
+		if h.metricsPath != "" {
+			app.Get(h.metricsPath, h.metrics.Handler())
+		}
+	}
+	// предварительные запросы браузеров не требуют аутентификации
+	app.Use(h.cors.Handle)
+	// пробы не требуют аутентификации и не ограничиваются
+	app.Get("/healthz", h.health.Liveness)
+	app.Get("/readyz", h.health.Readiness)
+
+	app.Use(h.auth.Handle)
+	app.Use(h.limiter.Handle)
+	app.Use(bufferBody(h.http.BodyLimit, "/admin/seed", "/admin/import"))
 
 	app.Get("/swagger/*", swagger.HandlerDefault)
 
-	posts := app.Group("/posts")
+	read := h.auth.Require(models.ScopePostsRead)
+	write := h.auth.Require(models.ScopePostsWrite)
+	limit := deadline.New(h.http.RequestTimeout)
+
+	posts := app.Group("/posts", h.maintenance.Handle)
 	{
-		posts.Get("", handle.ListPost)
-		posts.Get("/:id", handle.GetPost)
-		posts.Post("", handle.CreatePost)
-		posts.Put("", handle.UpdatePost)
-		posts.Delete("/:id", handle.DeletePost)
+		posts.Get("", read, limit, h.post.ListPost)
+		posts.Get("/stream", read, h.stream.PostsSSE)
+		// WebSocket выкатывается флагом, SSE остается доступен всем
+		posts.Get("/ws", read, h.features.Require(flagPostsWS), h.stream.PostsWS)
+		posts.Get("/:id", read, limit, h.post.GetPost)
+		posts.Post("", write, h.idem.Handle, limit, h.post.CreatePost)
+		posts.Put("", write, limit, h.post.UpdatePost)
+		posts.Delete("/:id", write, limit, h.post.DeletePost)
 	}
+
+	webhooks := app.Group("/webhooks", h.auth.Require(models.ScopeAdmin), limit)
+	{
+		webhooks.Get("", h.webhook.ListWebhooks)
+		webhooks.Get("/dead-letters", h.webhook.ListDeadLetters)
+		webhooks.Get("/:id", h.webhook.GetWebhook)
+		webhooks.Get("/:id/deliveries", h.webhook.ListDeliveries)
+		webhooks.Post("", h.webhook.CreateWebhook)
+		webhooks.Put("", h.webhook.UpdateWebhook)
+		webhooks.Delete("/:id", h.webhook.DeleteWebhook)
+	}
+
+	admin := app.Group("/admin", h.auth.Require(models.ScopeAdmin))
+	{
+		admin.Get("/api-keys", limit, h.apiKey.ListAPIKeys)
+		admin.Post("/api-keys", limit, h.apiKey.IssueAPIKey)
+		admin.Post("/api-keys/:id/rotate", limit, h.apiKey.RotateAPIKey)
+		admin.Delete("/api-keys/:id", limit, h.apiKey.RevokeAPIKey)
+		admin.Get("/outbox", limit, h.outbox.ListOutbox)
+		admin.Post("/outbox/:id/retry", limit, h.outbox.RetryOutbox)
+		admin.Get("/upstreams", limit, h.upstream.ListUpstreams)
+		admin.Get("/migrations", limit, h.migrate.ListMigrations)
+		admin.Post("/migrations/up", limit, h.migrate.MigrateUp)
+		admin.Post("/migrations/down", limit, h.migrate.MigrateDown)
+
+		// начальные данные, экспорт и импорт читаются и пишутся потоком и
+		// занимают столько, сколько нужно для объема данных. Загрузка данных
+		// меняет посты и в режиме обслуживания отклоняется, миграции для него
+		// и предназначены и остаются доступны.
+		admin.Post("/seed", h.maintenance.Handle, h.dataset.Seed)
+		admin.Get("/export", h.dataset.Export)
+		admin.Post("/import", h.maintenance.Handle, h.dataset.Import)
+	}
+
+	h.gateway.Mount(app)
 	return app
 }
 
+// bufferBody читает тело запроса целиком и отвечает 413, если оно больше
+// limit. Тела запросов к путям stream остаются потоком.
+func bufferBody(limit int, stream ...string) fiber.Handler {
+	if limit <= 0 {
+		limit = fiber.DefaultBodyLimit
+	}
+	return func(c *fiber.Ctx) error {
+		req := c.Request()
+		if !req.IsBodyStream() || slices.Contains(stream, c.Path()) {
+			return c.Next()
+		}
+		// непрочитанный остаток тела не дает разобрать следующий запрос,
+		// поэтому после отказа соединение закрывается
+		if req.Header.ContentLength() > limit {
+			c.Context().SetConnectionClose()
+			return fiber.ErrRequestEntityTooLarge
+		}
+		body, err := io.ReadAll(io.LimitReader(req.BodyStream(), int64(limit)+1))
+		if err != nil {
+			c.Context().SetConnectionClose()
+			return fiber.NewError(fiber.StatusBadRequest, "read body: "+err.Error())
+		}
+		if len(body) > limit {
+			c.Context().SetConnectionClose()
+			return fiber.ErrRequestEntityTooLarge
+		}
+		req.SetBody(body)
+		return c.Next()
+	}
+}
+
 func errorHandler(c *fiber.Ctx, err error) error {
-	slog.Debug(
+	slog.DebugContext(c.UserContext(),
 		fmt.Sprintf("resp uri=%s body=%s code=%d",
 			c.Request().URI().RequestURI(),
 			c.Response().Body(),
 			c.Response().StatusCode()),
 		slog.Any("error", err),
 	)
+	// отказ до чтения тела, передаваемого потоком (импорт в режиме
+	// обслуживания, запрос без ключа), оставил бы его остаток в соединении
+	if c.Request().IsBodyStream() {
+		c.Context().SetConnectionClose()
+	}
+
+	if errors.Is(err, apperr.ErrCircuitOpen) {
+		return problem(c, fiber.StatusServiceUnavailable, "CircuitOpen", err.Error())
+	}
+	if errors.Is(err, apperr.ErrMaintenance) {
+		return problem(c, fiber.StatusServiceUnavailable, "Maintenance", err.Error())
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return c.Status(504).JSON(fiber.Map{
+			"code":        "GatewayTimeout",
+			"description": "Превышено время обработки запроса",
+			"request_id":  logger.RequestID(c.UserContext()),
+		})
+	}
+	if errors.Is(err, context.Canceled) {
+		// клиент уже не ждет ответа, код 499 служит только для логов и метрик
+		return c.SendStatus(statusClientClosedRequest)
+	}
 
 	// check fiber error
 	if e, ok := err.(*fiber.Error); ok {
@@ -43,26 +228,79 @@ func errorHandler(c *fiber.Ctx, err error) error {
 			return c.Status(400).JSON(fiber.Map{
 				"code":        "BadRequest",
 				"description": e.Message,
+				"request_id":  logger.RequestID(c.UserContext()),
 			})
 		case fiber.StatusNotFound:
 			return c.Status(404).JSON(fiber.Map{
 				"code":        "NotFound",
 				"description": e.Message,
+				"request_id":  logger.RequestID(c.UserContext()),
 			})
 		case fiber.StatusUnauthorized:
 			return c.Status(401).JSON(fiber.Map{
 				"code":        "Unauthorized",
 				"description": "Недействительный токен аутентификации",
+				"request_id":  logger.RequestID(c.UserContext()),
+			})
+		case fiber.StatusForbidden:
+			return c.Status(403).JSON(fiber.Map{
+				"code":        "Forbidden",
+				"description": e.Message,
+				"request_id":  logger.RequestID(c.UserContext()),
 			})
 		case fiber.StatusMethodNotAllowed:
 			return c.Status(405).JSON(fiber.Map{
 				"code":        "Method Not Allowed",
 				"description": "Метод не поддерживается",
+				"request_id":  logger.RequestID(c.UserContext()),
+			})
+		case fiber.StatusUpgradeRequired:
+			return c.Status(426).JSON(fiber.Map{
+				"code":        "UpgradeRequired",
+				"description": "Требуется WebSocket соединение",
+				"request_id":  logger.RequestID(c.UserContext()),
+			})
+		case fiber.StatusBadGateway:
+			return c.Status(502).JSON(fiber.Map{
+				"code":        "BadGateway",
+				"description": e.Message,
+				"request_id":  logger.RequestID(c.UserContext()),
+			})
+		case fiber.StatusServiceUnavailable:
+			return c.Status(503).JSON(fiber.Map{
+				"code":        "ServiceUnavailable",
+				"description": e.Message,
+				"request_id":  logger.RequestID(c.UserContext()),
+			})
+		case fiber.StatusGatewayTimeout:
+			return c.Status(504).JSON(fiber.Map{
+				"code":        "GatewayTimeout",
+				"description": e.Message,
+				"request_id":  logger.RequestID(c.UserContext()),
+			})
+		case fiber.StatusUnprocessableEntity:
+			return c.Status(422).JSON(fiber.Map{
+				"code":        "UnprocessableEntity",
+				"description": e.Message,
+				"request_id":  logger.RequestID(c.UserContext()),
+			})
+		case fiber.StatusTooManyRequests:
+			return c.Status(429).JSON(fiber.Map{
+				"code":        "TooManyRequests",
+				"description": "Превышен лимит запросов",
+				"request_id":  logger.RequestID(c.UserContext()),
+			})
+		case fiber.StatusRequestEntityTooLarge:
+			return c.Status(413).JSON(fiber.Map{
+				"code":        "PayloadTooLarge",
+				"description": "Превышен размер тела запроса",
+				"request_id":  logger.RequestID(c.UserContext()),
 			})
 		case fiber.StatusConflict:
 			return c.Status(409).JSON(fiber.Map{
 				"code":        "Conflict",
 				"description": e.Message,
+				"request_id":  logger.RequestID(c.UserContext()),
 			})
 		}
 	}
@@ -70,5 +308,19 @@ func errorHandler(c *fiber.Ctx, err error) error {
 	return c.Status(500).JSON(fiber.Map{
 		"code":        "InternalServerError",
 		"description": err.Error(),
+		"request_id":  logger.RequestID(c.UserContext()),
 	})
 }
+
+// problem отвечает в формате application/problem+json (RFC 9457).
+func problem(c *fiber.Ctx, status int, code, detail string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"type":        "about:blank",
+		"title":       utils.StatusMessage(status),
+		"status":      status,
+		"detail":      detail,
+		"code":        code,
+		"description": detail,
+		"request_id":  logger.RequestID(c.UserContext()),
+	}, "application/problem+json")
+}
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+	"github.com/mtvy/blog-api-gateway/internal/cors"
+	"github.com/mtvy/blog-api-gateway/internal/feature/featuretest"
+	"github.com/mtvy/blog-api-gateway/internal/gateway"
+	"github.com/mtvy/blog-api-gateway/internal/handler"
+	"github.com/mtvy/blog-api-gateway/internal/health"
+	"github.com/mtvy/blog-api-gateway/internal/idempotency"
+	"github.com/mtvy/blog-api-gateway/internal/maintenance"
+	"github.com/mtvy/blog-api-gateway/internal/ratelimit"
+	"github.com/mtvy/blog-api-gateway/internal/repository"
+	"github.com/mtvy/blog-api-gateway/internal/stream"
+	"github.com/mtvy/blog-api-gateway/internal/usecase"
+	"github.com/mtvy/blog-api-gateway/migrations"
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestHandlers собирает обработчики над хранилищем в памяти. Ключ
+// authCfg.BootstrapKey регистрируется с правом admin.
+func newTestHandlers(t *testing.T, authCfg auth.Config) handlers {
+	t.Helper()
+
+	repo := repository.NewPostProvider()
+	hub := stream.NewHub(10, 10)
+	t.Cleanup(hub.Close)
+	gw, err := gateway.New(gateway.Config{})
+	require.NoError(t, err)
+	limiter, err := ratelimit.New(ratelimit.Config{}, ratelimit.NewMemoryStore())
+	require.NoError(t, err)
+	corsRules, err := cors.New(cors.Config{})
+	require.NoError(t, err)
+	apiKeyUC := usecase.NewAPIKeyProvider(repository.NewAPIKeyProvider())
+	if authCfg.BootstrapKey != "" {
+		require.NoError(t, apiKeyUC.Bootstrap(context.Background(), authCfg.BootstrapKey))
+	}
+
+	return handlers{
+		post:     handler.New(usecase.NewPostProvider(repo)),
+		stream:   handler.NewStream(hub, time.Second),
+		webhook:  handler.NewWebhook(usecase.NewWebhookProvider(repository.NewWebhookProvider(10))),
+		outbox:   handler.NewOutbox(usecase.NewOutboxProvider(repo)),
+		upstream: handler.NewUpstream(gw),
+		migrate:  handler.NewMigration(migrations.New(repo)),
+		dataset:  handler.NewDataset(usecase.NewDatasetProvider(repo)),
+		gateway:  gw,
+		apiKey:   handler.NewAPIKey(apiKeyUC),
+		cors:     corsRules,
+		auth:     auth.New(authCfg, apiKeyUC),
+		limiter:  limiter,
+		idem:     idempotency.New(idempotency.Config{TTL: time.Minute}),
+		health:   health.New(health.Config{Timeout: time.Second}),
+
+		maintenance: maintenance.New(maintenance.Config{RetryAfter: time.Second}),
+		features:    featuretest.New(t, map[string]bool{flagPostsWS: true}),
+	}
+}
+
+func TestRouter_Readiness(t *testing.T) {
+	h := newTestHandlers(t, auth.Config{})
+	h.health.Register("repository", 0, func(context.Context) error { return nil })
+	h.health.RegisterOptional("gateway", 0, func(context.Context) error {
+		return errors.New("no healthy upstream")
+	})
+	h.health.Describe("maintenance", func() any { return h.maintenance.Enabled() })
+
+	app := getRouter(h)
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "optional dependencies do not affect readiness")
+
+	var report health.Report
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.Equal(t, health.StatusDegraded, report.Status)
+	assert.Contains(t, report.Checks, "repository")
+	assert.Contains(t, report.Dependencies, "gateway")
+	assert.Equal(t, map[string]any{"maintenance": false}, report.State)
+}
+
+func TestRouter_Admin(t *testing.T) {
+	const key = "test-admin-key-0123456789abcdefgh"
+	app := getRouter(newTestHandlers(t, auth.Config{Enabled: true, BootstrapKey: key}))
+
+	testCases := []struct {
+		name     string
+		path     string
+		key      string
+		wantCode int
+	}{
+		{name: "anonymous", path: "/admin/upstreams", wantCode: http.StatusUnauthorized},
+		{name: "admin", path: "/admin/upstreams", key: key, wantCode: http.StatusOK},
+		{name: "api_keys", path: "/admin/api-keys", key: key, wantCode: http.StatusOK},
+		{name: "migrations", path: "/admin/migrations", key: key, wantCode: http.StatusOK},
+		{name: "outbox", path: "/admin/outbox", key: key, wantCode: http.StatusOK},
+		{name: "export", path: "/admin/export", key: key, wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.key != "" {
+				req.Header.Set(auth.HeaderAPIKey, tc.key)
+			}
+			resp, err := app.Test(req, -1)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantCode, resp.StatusCode)
+		})
+	}
+}
+
+func TestRouter_AdminMaintenance(t *testing.T) {
+	h := newTestHandlers(t, auth.Config{})
+	h.maintenance.Set(true)
+	h.http.BodyLimit = 1024
+	app := getRouter(h)
+
+	testCases := []struct {
+		name     string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{name: "seed", path: "/admin/seed", wantCode: http.StatusServiceUnavailable},
+		{name: "import", path: "/admin/import", body: strings.Repeat(`{"id":1,"title":"A","author":"X"}`+"\n", 100), wantCode: http.StatusServiceUnavailable},
+		{name: "migrations", path: "/admin/migrations/up", wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/x-ndjson")
+			resp, err := app.Test(req, -1)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantCode, resp.StatusCode)
+		})
+	}
+}
+
+func TestRouter_FeatureFlag(t *testing.T) {
+	h := newTestHandlers(t, auth.Config{})
+	app := getRouter(h)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/posts/ws", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUpgradeRequired, resp.StatusCode, "flag is on")
+
+	featuretest.Force(t, h.features, flagPostsWS, false)
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/posts/ws", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "flag is off")
+}
+
+func TestRouter_BodyLimit(t *testing.T) {
+	h := newTestHandlers(t, auth.Config{})
+	h.http.BodyLimit = 1024
+	app := getRouter(h)
+
+	post := fmt.Sprintf(`{"title":"A","author":"X","content":%q}`, strings.Repeat("x", 2048))
+	req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(post))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	var lines strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&lines, "{\"id\":%d,\"title\":\"Post %d\",\"author\":\"X\"}\n", i, i)
+	}
+	require.Greater(t, lines.Len(), h.http.BodyLimit)
+	req = httptest.NewRequest(http.MethodPost, "/admin/import?format=jsonl", strings.NewReader(lines.String()))
+	resp, err = app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "imports are streamed past the body limit")
+}
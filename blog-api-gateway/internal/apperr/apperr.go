@@ -3,5 +3,10 @@ package apperr
 import "errors"
 
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound     = errors.New("not found")
+	ErrCircuitOpen  = errors.New("circuit breaker is open")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrRevoked      = errors.New("revoked")
+	ErrConflict     = errors.New("conflict")
+	ErrMaintenance  = errors.New("service is in maintenance mode")
 )
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/deadline"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	HeaderAPIKey = "X-API-Key"
+
+	KindAPIKey = "api_key"
+
+	identityLocal = "identity"
+)
+
+// Config: при Enabled маршруты требуют аутентификации и прав. BootstrapKey
+// регистрируется при запуске как ключ администратора.
+type Config struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	BootstrapKey string `mapstructure:"bootstrap_key" validate:"omitempty,min=32" secret:"true"`
+}
+
+// Identity - аутентифицированный клиент и его права.
+type Identity struct {
+	Subject string
+	Kind    string
+	Scopes  []string
+}
+
+// HasScope возвращает true, если у клиента есть право scope. Право admin
+// включает все остальные.
+func (i Identity) HasScope(scope string) bool {
+	return slices.Contains(i.Scopes, models.ScopeAdmin) || slices.Contains(i.Scopes, scope)
+}
+
+func FromContext(c *fiber.Ctx) (*Identity, bool) {
+	id, ok := c.Locals(identityLocal).(*Identity)
+	return id, ok
+}
+
+func WithIdentity(c *fiber.Ctx, id *Identity) {
+	c.Locals(identityLocal, id)
+}
+
+type apiKeyAuthenticator interface {
+	Authenticate(ctx context.Context, raw string) (*models.APIKeyDTO, error)
+}
+
+type Authenticator struct {
+	enabled bool
+	keys    apiKeyAuthenticator
+}
+
+func New(cfg Config, keys apiKeyAuthenticator) *Authenticator {
+	return &Authenticator{
+		enabled: cfg.Enabled,
+		keys:    keys,
+	}
+}
+
+// Handle аутентифицирует клиента по заголовку X-API-Key. Запрос без ключа
+// проходит анонимно, решение о доступе принимает Require.
+func (a *Authenticator) Handle(c *fiber.Ctx) error {
+	raw := c.Get(HeaderAPIKey)
+	if raw == "" {
+		return c.Next()
+	}
+
+	key, err := a.keys.Authenticate(c.UserContext(), raw)
+	if err != nil {
+		if errors.Is(err, apperr.ErrUnauthorized) {
+			return fiber.NewError(http.StatusUnauthorized)
+		}
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "authenticate api key", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	WithIdentity(c, &Identity{
+		Subject: KindAPIKey + ":" + strconv.FormatUint(key.ID, 10),
+		Kind:    KindAPIKey,
+		Scopes:  key.Scopes,
+	})
+	return c.Next()
+}
+
+// Require пропускает запрос, если у клиента есть право scope. Без
+// включенной аутентификации проверка не выполняется.
+func (a *Authenticator) Require(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !a.enabled {
+			return c.Next()
+		}
+
+		id, ok := FromContext(c)
+		if !ok {
+			return fiber.NewError(http.StatusUnauthorized)
+		}
+		if !id.HasScope(scope) {
+			return fiber.NewError(http.StatusForbidden, "scope "+scope+" is required")
+		}
+		return c.Next()
+	}
+}
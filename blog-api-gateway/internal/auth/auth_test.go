@@ -0,0 +1,112 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/internal/repository"
+	"github.com/mtvy/blog-api-gateway/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestApp(enabled bool, uc *usecase.APIKeyUsecase) *fiber.App {
+	a := auth.New(auth.Config{Enabled: enabled}, uc)
+
+	app := fiber.New()
+	app.Use(a.Handle)
+	ok := func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	}
+	app.Get("/posts", a.Require(models.ScopePostsRead), ok)
+	app.Post("/posts", a.Require(models.ScopePostsWrite), ok)
+	app.Get("/admin", a.Require(models.ScopeAdmin), ok)
+	return app
+}
+
+func call(t *testing.T, app *fiber.App, method, path, key string) int {
+	req := httptest.NewRequest(method, path, nil)
+	if key != "" {
+		req.Header.Set(auth.HeaderAPIKey, key)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	return resp.StatusCode
+}
+
+func TestAuthenticator_Scopes(t *testing.T) {
+	uc := usecase.NewAPIKeyProvider(repository.NewAPIKeyProvider())
+	app := newTestApp(true, uc)
+
+	reader, err := uc.IssueAPIKey(context.Background(), models.APIKeyDTO{Name: "reader", Scopes: []string{models.ScopePostsRead}})
+	require.NoError(t, err)
+	admin, err := uc.IssueAPIKey(context.Background(), models.APIKeyDTO{Name: "admin", Scopes: []string{models.ScopeAdmin}})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		want   int
+	}{
+		{name: "anonymous", method: http.MethodGet, path: "/posts", want: http.StatusUnauthorized},
+		{name: "unknown_key", method: http.MethodGet, path: "/posts", key: "bk_unknown", want: http.StatusUnauthorized},
+		{name: "read", method: http.MethodGet, path: "/posts", key: reader.Key, want: http.StatusOK},
+		{name: "write_without_scope", method: http.MethodPost, path: "/posts", key: reader.Key, want: http.StatusForbidden},
+		{name: "admin_without_scope", method: http.MethodGet, path: "/admin", key: reader.Key, want: http.StatusForbidden},
+		{name: "admin_implies_write", method: http.MethodPost, path: "/posts", key: admin.Key, want: http.StatusOK},
+		{name: "admin", method: http.MethodGet, path: "/admin", key: admin.Key, want: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, call(t, app, tc.method, tc.path, tc.key))
+		})
+	}
+}
+
+func TestAuthenticator_Lifecycle(t *testing.T) {
+	uc := usecase.NewAPIKeyProvider(repository.NewAPIKeyProvider())
+	app := newTestApp(true, uc)
+
+	key, err := uc.IssueAPIKey(context.Background(), models.APIKeyDTO{Name: "script", Scopes: []string{models.ScopePostsRead}})
+	require.NoError(t, err)
+	assert.Equal(t, key.Key[:len(key.Prefix)], key.Prefix)
+	assert.NotContains(t, key.Hash, key.Key)
+
+	assert.Equal(t, http.StatusOK, call(t, app, http.MethodGet, "/posts", key.Key))
+	keys, err := uc.ListAPIKeys(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, keys[0].LastUsedAt)
+
+	rotated, err := uc.RotateAPIKey(context.Background(), key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, call(t, app, http.MethodGet, "/posts", key.Key))
+	assert.Equal(t, http.StatusOK, call(t, app, http.MethodGet, "/posts", rotated.Key))
+
+	require.NoError(t, uc.RevokeAPIKey(context.Background(), key.ID))
+	assert.Equal(t, http.StatusUnauthorized, call(t, app, http.MethodGet, "/posts", rotated.Key))
+	_, err = uc.RotateAPIKey(context.Background(), key.ID)
+	assert.Error(t, err)
+
+	expiresAt := time.Now().Add(-time.Minute)
+	expired, err := uc.IssueAPIKey(context.Background(), models.APIKeyDTO{Name: "expired", Scopes: []string{models.ScopePostsRead}, ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, call(t, app, http.MethodGet, "/posts", expired.Key))
+}
+
+func TestAuthenticator_Disabled(t *testing.T) {
+	uc := usecase.NewAPIKeyProvider(repository.NewAPIKeyProvider())
+	app := newTestApp(false, uc)
+
+	assert.Equal(t, http.StatusOK, call(t, app, http.MethodPost, "/posts", ""))
+	assert.Equal(t, http.StatusUnauthorized, call(t, app, http.MethodPost, "/posts", "bk_unknown"))
+}
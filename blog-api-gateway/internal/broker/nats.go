@@ -0,0 +1,93 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// SchemaVersion версия формата сообщений о событиях постов.
+const SchemaVersion = 1
+
+type Config struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	URLs          string        `mapstructure:"urls" validate:"required_if=Enabled true,nats_urls"`
+	SubjectPrefix string        `mapstructure:"subject_prefix" validate:"required_if=Enabled true"`
+	JetStream     bool          `mapstructure:"jetstream"`
+	Timeout       time.Duration `mapstructure:"timeout" validate:"gt=0"`
+}
+
+type Message struct {
+	SchemaVersion int `json:"schema_version"`
+	models.PostEvent
+}
+
+// NATSPublisher публикует события постов в subject вида
+// "<prefix>.created|updated|deleted". ID события передается в заголовке
+// Nats-Msg-Id, что позволяет JetStream отбрасывать дубликаты.
+type NATSPublisher struct {
+	conn   *nats.Conn
+	js     nats.JetStreamContext
+	prefix string
+}
+
+func NewNATSPublisher(cfg Config) (*NATSPublisher, error) {
+	conn, err := nats.Connect(cfg.URLs,
+		nats.Name("blog-api-gateway"),
+		nats.Timeout(cfg.Timeout),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "nats connect")
+	}
+
+	p := &NATSPublisher{
+		conn:   conn,
+		prefix: cfg.SubjectPrefix,
+	}
+
+	if cfg.JetStream {
+		if p.js, err = conn.JetStream(nats.MaxWait(cfg.Timeout)); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "jetstream context")
+		}
+	}
+	return p, nil
+}
+
+func (p *NATSPublisher) Subject(eventType models.PostEventType) string {
+	return p.prefix + "." + strings.TrimPrefix(string(eventType), "post.")
+}
+
+// Send при включенном JetStream дожидается подтверждения от сервера.
+func (p *NATSPublisher) Send(event models.PostEvent) error {
+	data, err := json.Marshal(Message{SchemaVersion: SchemaVersion, PostEvent: event})
+	if err != nil {
+		return errors.Wrap(err, "marshal message")
+	}
+
+	msg := nats.NewMsg(p.Subject(event.Type))
+	msg.Data = data
+	msg.Header.Set(nats.MsgIdHdr, event.ID)
+
+	if p.js != nil {
+		_, err = p.js.PublishMsg(msg)
+		return errors.Wrap(err, "jetstream publish")
+	}
+	return errors.Wrap(p.conn.PublishMsg(msg), "nats publish")
+}
+
+// Ping проверяет связь с сервером NATS.
+func (p *NATSPublisher) Ping(ctx context.Context) error {
+	return errors.Wrap(p.conn.FlushWithContext(ctx), "nats flush")
+}
+
+// Close дожидается отправки буферизованных сообщений и закрывает соединение.
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
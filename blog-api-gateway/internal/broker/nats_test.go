@@ -0,0 +1,91 @@
+package broker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runServer(t *testing.T) *server.Server {
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+
+	go srv.Start()
+	require.True(t, srv.ReadyForConnections(5*time.Second))
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+func TestNATSPublisher_Send(t *testing.T) {
+	srv := runServer(t)
+
+	conn, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sub, err := conn.SubscribeSync("blog.posts.>")
+	require.NoError(t, err)
+
+	publisher, err := NewNATSPublisher(Config{URLs: srv.ClientURL(), SubjectPrefix: "blog.posts", Timeout: time.Second})
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	event := models.NewPostEvent(models.PostUpdated, models.PostDTO{ID: 7, Title: "Title 7"})
+	require.NoError(t, publisher.Send(event))
+
+	msg, err := sub.NextMsg(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "blog.posts.updated", msg.Subject)
+	assert.Equal(t, event.ID, msg.Header.Get(nats.MsgIdHdr))
+
+	var got Message
+	require.NoError(t, json.Unmarshal(msg.Data, &got))
+	assert.Equal(t, SchemaVersion, got.SchemaVersion)
+	assert.Equal(t, event.ID, got.ID)
+	assert.Equal(t, uint64(7), got.PostID)
+	assert.Equal(t, "Title 7", got.Post.Title)
+}
+
+func TestNATSPublisher_JetStreamDeduplication(t *testing.T) {
+	srv := runServer(t)
+
+	conn, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	js, err := conn.JetStream()
+	require.NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:       "POSTS",
+		Subjects:   []string{"blog.posts.>"},
+		Duplicates: time.Minute,
+	})
+	require.NoError(t, err)
+
+	publisher, err := NewNATSPublisher(Config{URLs: srv.ClientURL(), SubjectPrefix: "blog.posts", JetStream: true, Timeout: time.Second})
+	require.NoError(t, err)
+
+	created := models.NewPostEvent(models.PostCreated, models.PostDTO{ID: 1})
+	require.NoError(t, publisher.Send(created))
+	require.NoError(t, publisher.Send(created))
+	require.NoError(t, publisher.Send(models.NewPostEvent(models.PostDeleted, models.PostDTO{ID: 1})))
+	require.NoError(t, publisher.Close())
+
+	info, err := js.StreamInfo("POSTS")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), info.State.Msgs)
+}
@@ -0,0 +1,42 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version и Commit задаются при сборке:
+//
+//	go build -ldflags "-X github.com/mtvy/blog-api-gateway/internal/buildinfo.Version=v1.2.0 \
+//	  -X github.com/mtvy/blog-api-gateway/internal/buildinfo.Commit=$(git rev-parse HEAD)"
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get возвращает сведения о сборке. Если Commit не задан, используется
+// ревизия, которую go build записывает из git.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		GoVersion: runtime.Version(),
+	}
+	if info.Commit != "" {
+		return info
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			if s.Key == "vcs.revision" {
+				info.Commit = s.Value
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,18 @@
+package buildinfo
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	info := Get()
+	assert.Equal(t, "dev", info.Version)
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+
+	Version, Commit = "v1.2.0", "abc123"
+	t.Cleanup(func() { Version, Commit = "dev", "" })
+	assert.Equal(t, Info{Version: "v1.2.0", Commit: "abc123", GoVersion: runtime.Version()}, Get())
+}
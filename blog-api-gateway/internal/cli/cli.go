@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/mtvy/blog-api-gateway/config"
+	"github.com/mtvy/blog-api-gateway/internal/app"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvAPIKey - переменная окружения с ключом администратора для команд,
+// обращающихся к запущенному сервису.
+const EnvAPIKey = config.EnvPrefix + "_API_KEY"
+
+const usage = `Usage: blog-api-gateway [command] [flags]
+
+Commands:
+  serve                     Start the server (default)
+  migrate up|down|status    Apply, roll back or list migrations
+  seed --file <path>        Add posts from a JSON or JSON Lines file
+  export [--out <path>]     Export all posts as JSON (the seed format) or
+                            JSON Lines (--format jsonl or a .jsonl file)
+  import --file <path>      Import exported posts keeping their ids;
+                            --mode fail|skip|overwrite|remap for taken ids
+  config print              Print the effective configuration, secrets redacted
+  config validate           Validate the configuration
+  help                      Show this help
+
+All commands accept --config <path>. Storage lives in the server process, so
+migrate, seed, export and import call the admin API of a running server:
+  --addr <url>              Server address (default: from http.port and http.tls)
+  --api-key <key>           Admin API key (default: $BLOG_APIGATEWAY_API_KEY,
+                            then auth.bootstrap_key)
+`
+
+var errUsage = errors.New("invalid usage")
+
+// Run выполняет команду из args (без имени программы). Без команды
+// запускается сервер.
+func Run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return serve(args, stdout)
+	}
+
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "serve":
+		return serve(args, stdout)
+	case "migrate":
+		return runMigrate(args, stdout, stderr)
+	case "seed":
+		return runSeed(args, stdout, stderr)
+	case "export":
+		return runExport(args, stdout, stderr)
+	case "import":
+		return runImport(args, stdout, stderr)
+	case "config":
+		return runConfig(args, stdout, stderr)
+	case "help":
+		_, err := io.WriteString(stdout, usage)
+		return err
+	default:
+		return usageError(stderr, "unknown command %q", cmd)
+	}
+}
+
+// serve запускает сервер или, если среди args есть флаг справки, выводит
+// справку.
+func serve(args []string, stdout io.Writer) error {
+	if slices.ContainsFunc(args, isHelpFlag) {
+		_, err := io.WriteString(stdout, usage)
+		return err
+	}
+	return app.Run(config.Loader{Args: args})
+}
+
+func isHelpFlag(arg string) bool {
+	switch arg {
+	case "-h", "-help", "--help":
+		return true
+	}
+	return false
+}
+
+func usageError(stderr io.Writer, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	fmt.Fprintf(stderr, "%s\n\n%s", msg, usage)
+	return errors.Wrap(errUsage, msg)
+}
+
+// flags - общие флаги команд.
+type flags struct {
+	set        *flag.FlagSet
+	configFile string
+	addr       string
+	apiKey     string
+}
+
+func newFlags(name string, stderr io.Writer, remote bool) *flags {
+	f := &flags{set: flag.NewFlagSet(name, flag.ContinueOnError)}
+	f.set.SetOutput(stderr)
+	f.set.StringVar(&f.configFile, "config", "", "path to the configuration file")
+	if remote {
+		f.set.StringVar(&f.addr, "addr", "", "server address")
+		f.set.StringVar(&f.apiKey, "api-key", "", "admin API key")
+	}
+	return f
+}
+
+func (f *flags) parse(args []string) error {
+	if err := f.set.Parse(args); err != nil {
+		return errors.Wrap(errUsage, err.Error())
+	}
+	if f.set.NArg() > 0 {
+		return usageError(f.set.Output(), "unexpected arguments: %s", strings.Join(f.set.Args(), " "))
+	}
+	return nil
+}
+
+func (f *flags) loader() config.Loader {
+	if f.configFile == "" {
+		return config.Loader{}
+	}
+	return config.Loader{Args: []string{"--config", f.configFile}}
+}
+
+// client создает клиент admin API. Адрес и ключ, не заданные флагами,
+// берутся из окружения и конфигурации.
+func (f *flags) client() (*client, error) {
+	addr, apiKey := f.addr, f.apiKey
+	if apiKey == "" {
+		apiKey = os.Getenv(EnvAPIKey)
+	}
+	if addr == "" || apiKey == "" {
+		cfg, err := f.loader().Load()
+		if err != nil {
+			return nil, errors.Wrap(err, "parse cfg")
+		}
+		if addr == "" {
+			scheme := "http"
+			if cfg.HTTP.TLS.Enabled() {
+				scheme = "https"
+			}
+			addr = fmt.Sprintf("%s://localhost:%d", scheme, cfg.HTTP.Port)
+		}
+		if apiKey == "" {
+			apiKey = cfg.Auth.BootstrapKey
+		}
+	}
+	return newClient(addr, apiKey), nil
+}
+
+// context отменяется по SIGINT.
+func commandContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+func runConfig(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return usageError(stderr, "config: missing subcommand")
+	}
+	sub, args := args[0], args[1:]
+	if sub != "print" && sub != "validate" {
+		return usageError(stderr, "config: unknown subcommand %q", sub)
+	}
+
+	f := newFlags("config "+sub, stderr, false)
+	if err := f.parse(args); err != nil {
+		return err
+	}
+	cfg, err := f.loader().Load()
+	if err != nil {
+		return err
+	}
+
+	if sub == "validate" {
+		_, err := fmt.Fprintln(stdout, "configuration is valid")
+		return err
+	}
+	enc := yaml.NewEncoder(stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg.Redacted()); err != nil {
+		return errors.Wrap(err, "encode configuration")
+	}
+	return enc.Close()
+}
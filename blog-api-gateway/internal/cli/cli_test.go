@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAPIKey = "test-admin-key"
+
+func run(t *testing.T, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	err := Run(args, &stdout, io.Discard)
+	return stdout.String(), err
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// newTestServer отвечает на запросы admin API ответом resp и сохраняет
+// тело последнего запроса в body.
+func newTestServer(t *testing.T, method, path string, resp any, body *[]byte) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(auth.HeaderAPIKey) != testAPIKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"code":"Unauthorized","description":"invalid key"}`)
+			return
+		}
+		if r.Method != method || r.URL.Path != path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if body != nil {
+			*body, _ = io.ReadAll(r.Body)
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestRun_Config(t *testing.T) {
+	path := writeFile(t, "config.yml", "http:\n  port: 9090\nauth:\n  bootstrap_key: 0123456789abcdef0123456789abcdef\n")
+
+	out, err := run(t, "config", "validate", "--config", path)
+	require.NoError(t, err)
+	assert.Equal(t, "configuration is valid\n", out)
+
+	out, err = run(t, "config", "print", "--config", path)
+	require.NoError(t, err)
+	assert.Contains(t, out, "port: 9090")
+	assert.Contains(t, out, "request_timeout: 10s")
+	assert.Contains(t, out, "bootstrap_key: '[REDACTED]'")
+	assert.NotContains(t, out, "0123456789abcdef")
+
+	invalid := writeFile(t, "config.yml", "http:\n  port: 70000\n")
+	_, err = run(t, "config", "validate", "--config", invalid)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http.port(port)")
+}
+
+func TestRun_Migrate(t *testing.T) {
+	addr := newTestServer(t, http.MethodGet, "/admin/migrations", map[string]any{
+		"migrations": []map[string]any{
+			{"version": 1, "name": "initial_posts", "applied": true},
+			{"version": 2, "name": "comments", "applied": false},
+		},
+	}, nil)
+
+	out, err := run(t, "migrate", "status", "--addr", addr, "--api-key", testAPIKey)
+	require.NoError(t, err)
+	assert.Equal(t, "VERSION  NAME           STATUS\n1        initial_posts  applied\n2        comments       pending\n", out)
+
+	_, err = run(t, "migrate", "status", "--addr", addr, "--api-key", "wrong")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401 Unauthorized: invalid key")
+}
+
+func TestRun_Seed(t *testing.T) {
+	var body []byte
+	addr := newTestServer(t, http.MethodPost, "/admin/seed", map[string]int{"seeded": 2}, &body)
+	file := writeFile(t, "posts.json", `{"posts":[{"title":"A","author":"X"},{"title":"B","author":"Y"}]}`)
+
+	out, err := run(t, "seed", "--file", file, "--addr", addr, "--api-key", testAPIKey)
+	require.NoError(t, err)
+	assert.Equal(t, "seeded 2 posts\n", out)
+
+	var sent models.DatasetDTO
+	require.NoError(t, json.Unmarshal(body, &sent))
+	assert.Equal(t, []models.PostRecordDTO{{Title: "A", Author: "X"}, {Title: "B", Author: "Y"}}, sent.Posts)
+}
+
+func TestRun_Import(t *testing.T) {
+	var body []byte
+	addr := newTestServer(t, http.MethodPost, "/admin/import", models.ImportProgressDTO{
+		Status: models.ImportDone,
+		ImportReportDTO: models.ImportReportDTO{
+			Processed: 2, Created: 2,
+			Remapped: map[uint64]uint64{7: 101, 3: 102},
+		},
+	}, &body)
+	lines := "{\"id\":7,\"title\":\"A\",\"author\":\"X\"}\n{\"id\":3,\"title\":\"B\",\"author\":\"Y\"}\n"
+	file := writeFile(t, "posts.jsonl", lines)
+
+	out, err := run(t, "import", "--file", file, "--mode", "remap", "--addr", addr, "--api-key", testAPIKey)
+	require.NoError(t, err)
+	assert.Equal(t, "imported 2 posts: 2 created, 0 updated, 0 skipped\n3 -> 102\n7 -> 101\n", out)
+	assert.Equal(t, lines, string(body))
+}
+
+func TestReadImportProgress(t *testing.T) {
+	testCases := []struct {
+		name   string
+		stream string
+		report models.ImportReportDTO
+		err    string
+		stderr string
+	}{
+		{
+			name: "done",
+			stream: `{"status":"running","processed":1000,"created":1000}` + "\n" +
+				`{"status":"done","processed":1500,"created":1500}` + "\n",
+			report: models.ImportReportDTO{Processed: 1500, Created: 1500},
+			stderr: "saved 1000 posts\n",
+		},
+		{
+			name:   "failed",
+			stream: `{"status":"failed","processed":1000,"created":1000,"code":400,"error":"post 1001: title is required"}` + "\n",
+			report: models.ImportReportDTO{Processed: 1000, Created: 1000},
+			err:    "import failed after 1000 posts: Bad Request: post 1001: title is required",
+		},
+		{
+			name:   "truncated",
+			stream: `{"status":"running","processed":1000,"created":1000}` + "\n",
+			err:    "unexpected EOF",
+			stderr: "saved 1000 posts\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			report, err := readImportProgress(strings.NewReader(tc.stream), &stderr)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.report, report)
+			assert.Equal(t, tc.stderr, stderr.String())
+		})
+	}
+}
+
+func TestRun_Export(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/export" || r.URL.Query().Get("format") != "jsonl" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Total-Count", "1")
+		_, _ = io.WriteString(w, "{\"id\":1,\"title\":\"A\",\"author\":\"X\",\"content\":\"text\"}\n")
+	}))
+	t.Cleanup(srv.Close)
+	out := filepath.Join(t.TempDir(), "export.jsonl")
+
+	stdout, err := run(t, "export", "--out", out, "--addr", srv.URL, "--api-key", testAPIKey)
+	require.NoError(t, err)
+	assert.Equal(t, "exported 1 posts to "+out+"\n", stdout)
+
+	raw, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"id\":1,\"title\":\"A\",\"author\":\"X\",\"content\":\"text\"}\n", string(raw))
+}
+
+func TestRun_Usage(t *testing.T) {
+	testCases := [][]string{
+		{"deploy"},
+		{"migrate"},
+		{"migrate", "sideways"},
+		{"config", "edit"},
+		{"seed"},
+		{"import", "--file", "a.json", "extra"},
+	}
+
+	for _, args := range testCases {
+		_, err := run(t, args...)
+		assert.ErrorIs(t, err, errUsage, args)
+	}
+
+	for _, args := range [][]string{{"help"}, {"--help"}, {"-h"}, {"-help"}, {"serve", "--help"}, {"--config", "config.yml", "-h"}} {
+		out, err := run(t, args...)
+		require.NoError(t, err, args)
+		assert.Contains(t, out, "Usage: blog-api-gateway", args)
+	}
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+	"github.com/pkg/errors"
+)
+
+// client вызывает admin API запущенного сервиса.
+type client struct {
+	addr   string
+	apiKey string
+	http   *http.Client
+}
+
+func newClient(addr, apiKey string) *client {
+	return &client{
+		addr:   strings.TrimSuffix(addr, "/"),
+		apiKey: apiKey,
+		http:   http.DefaultClient,
+	}
+}
+
+// do отправляет in в формате JSON и декодирует ответ в out.
+func (c *client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return errors.Wrap(err, "encode request")
+		}
+		body = bytes.NewReader(data)
+	}
+
+	resp, err := c.send(ctx, method, path, "application/json", "", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("decode %s %s response", method, path))
+	}
+	return nil
+}
+
+// send отправляет body с типом contentType и возвращает успешный ответ,
+// тело которого должен закрыть вызывающий. Непустой accept задает
+// ожидаемый тип ответа. Ответ с кодом ошибки возвращается ошибкой с
+// описанием сервиса.
+func (c *client) send(ctx context.Context, method, path, contentType, accept string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.addr+path, body)
+	if err != nil {
+		return nil, errors.Wrap(err, "create request")
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if c.apiKey != "" {
+		req.Header.Set(auth.HeaderAPIKey, c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s %s", method, path)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		var problem struct {
+			Description string `json:"description"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&problem)
+		return nil, errors.Errorf("%s %s: %s: %s", method, path, resp.Status, problem.Description)
+	}
+	return resp, nil
+}
@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/mtvy/blog-api-gateway/internal/dataset"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/migrations"
+	"github.com/pkg/errors"
+)
+
+func runMigrate(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return usageError(stderr, "migrate: missing subcommand")
+	}
+	sub, args := args[0], args[1:]
+
+	var method, path string
+	switch sub {
+	case "up":
+		method, path = http.MethodPost, "/admin/migrations/up"
+	case "down":
+		method, path = http.MethodPost, "/admin/migrations/down"
+	case "status":
+		method, path = http.MethodGet, "/admin/migrations"
+	default:
+		return usageError(stderr, "migrate: unknown subcommand %q", sub)
+	}
+
+	f := newFlags("migrate "+sub, stderr, true)
+	if err := f.parse(args); err != nil {
+		return err
+	}
+	c, err := f.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	var resp struct {
+		Migrations []migrations.Status `json:"migrations"`
+	}
+	if err := c.do(ctx, method, path, nil, &resp); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+	for _, m := range resp.Migrations {
+		status := "pending"
+		if m.Applied {
+			status = "applied"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", m.Version, m.Name, status)
+	}
+	return w.Flush()
+}
+
+func runSeed(args []string, stdout, stderr io.Writer) error {
+	f := newFlags("seed", stderr, true)
+	file := f.set.String("file", "", "file in the seed format or JSON Lines")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return usageError(stderr, "seed: --file is required")
+	}
+
+	var resp struct {
+		Seeded int `json:"seeded"`
+	}
+	err := upload(f, "/admin/seed", *file, dataset.FormatOf(*file), "", stderr, func(r io.Reader) error {
+		return errors.Wrap(json.NewDecoder(r).Decode(&resp), "decode response")
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "seeded %d posts\n", resp.Seeded)
+	return err
+}
+
+func runImport(args []string, stdout, stderr io.Writer) error {
+	f := newFlags("import", stderr, true)
+	file := f.set.String("file", "", "file in the export format")
+	mode := f.set.String("mode", string(models.ImportFail), "what to do with taken ids: fail, skip, overwrite or remap")
+	format := f.set.String("format", "", "json or jsonl (default: by file extension)")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return usageError(stderr, "import: --file is required")
+	}
+	if *format == "" {
+		*format = dataset.FormatOf(*file)
+	}
+
+	query := url.Values{"mode": {*mode}, "format": {*format}}
+	var report models.ImportReportDTO
+	err := upload(f, "/admin/import?"+query.Encode(), *file, *format, dataset.ContentTypeJSONL, stderr, func(r io.Reader) error {
+		var err error
+		report, err = readImportProgress(r, stderr)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "imported %d posts: %d created, %d updated, %d skipped\n",
+		report.Processed, report.Created, report.Updated, report.Skipped)
+	if err != nil || len(report.Remapped) == 0 {
+		return err
+	}
+	ids := make([]uint64, 0, len(report.Remapped))
+	for id := range report.Remapped {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		fmt.Fprintf(stdout, "%d -> %d\n", id, report.Remapped[id])
+	}
+	return nil
+}
+
+// readImportProgress читает поток хода импорта, сообщая в stderr о каждом
+// сохраненном пакете, и возвращает итог.
+func readImportProgress(r io.Reader, stderr io.Writer) (models.ImportReportDTO, error) {
+	dec := json.NewDecoder(r)
+	for {
+		var p models.ImportProgressDTO
+		if err := dec.Decode(&p); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return models.ImportReportDTO{}, errors.Wrap(err, "decode import progress")
+		}
+		switch p.Status {
+		case models.ImportRunning:
+			fmt.Fprintf(stderr, "saved %d posts\n", p.Processed)
+		case models.ImportDone:
+			return p.ImportReportDTO, nil
+		default:
+			return p.ImportReportDTO, errors.Errorf("import failed after %d posts: %s: %s",
+				p.Processed, http.StatusText(p.Code), p.Error)
+		}
+	}
+}
+
+// upload отправляет файл path по адресу target, сообщая в stderr о ходе
+// отправки, и передает тело ответа в read.
+func upload(f *flags, target, path, format, accept string, stderr io.Writer, read func(io.Reader) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "open data file")
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return errors.Wrap(err, "stat data file")
+	}
+
+	c, err := f.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	body := &progressReader{r: file, size: info.Size(), w: stderr}
+	resp, err := c.send(ctx, http.MethodPost, target, dataset.ContentType(format), accept, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return read(resp.Body)
+}
+
+// progressReader сообщает о каждых прочитанных 10% файла.
+type progressReader struct {
+	r        io.Reader
+	size     int64
+	read     int64
+	reported int64
+	w        io.Writer
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if p.size > 0 {
+		if percent := p.read * 100 / p.size; percent/10 > p.reported/10 {
+			p.reported = percent
+			fmt.Fprintf(p.w, "sent %d%% (%d of %d bytes)\n", percent, p.read, p.size)
+		}
+	}
+	return n, err
+}
+
+func runExport(args []string, stdout, stderr io.Writer) error {
+	f := newFlags("export", stderr, true)
+	out := f.set.String("out", "", "output file (default: stdout)")
+	format := f.set.String("format", "", "json or jsonl (default: by output file extension)")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = dataset.FormatOf(*out)
+	}
+	c, err := f.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	resp, err := c.send(ctx, http.MethodGet, "/admin/export?format="+url.QueryEscape(*format), "", "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	w := stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return errors.Wrap(err, "create output file")
+		}
+		defer file.Close()
+		w = file
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return errors.Wrap(err, "write export")
+	}
+	if *out != "" {
+		fmt.Fprintf(stdout, "exported %s posts to %s\n", resp.Header.Get("X-Total-Count"), *out)
+	}
+	return nil
+}
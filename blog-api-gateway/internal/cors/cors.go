@@ -0,0 +1,81 @@
+// Package cors отвечает на запросы браузеров с других источников по
+// правилам, которые меняются без перезапуска.
+package cors
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	fibercors "github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/pkg/errors"
+)
+
+// Config: AllowOrigins - источники вида https://example.com, допускается
+// "*" и поддомены https://*.example.com. Пустые AllowMethods и AllowHeaders
+// означают значения Fiber по умолчанию. MaxAge - срок кеширования ответа на
+// предварительный запрос.
+type Config struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	AllowOrigins     []string      `mapstructure:"allow_origins" validate:"required_if=Enabled true,dive,required"`
+	AllowMethods     []string      `mapstructure:"allow_methods" validate:"dive,alpha"`
+	AllowHeaders     []string      `mapstructure:"allow_headers"`
+	ExposeHeaders    []string      `mapstructure:"expose_headers"`
+	AllowCredentials bool          `mapstructure:"allow_credentials"`
+	MaxAge           time.Duration `mapstructure:"max_age" validate:"gte=0"`
+}
+
+// CORS - промежуточный обработчик с заменяемыми правилами.
+type CORS struct {
+	handler atomic.Pointer[fiber.Handler]
+}
+
+func New(cfg Config) (*CORS, error) {
+	c := &CORS{}
+	if err := c.Update(cfg); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Update заменяет правила. При ошибке правила не меняются.
+func (c *CORS) Update(cfg Config) error {
+	handler, err := newHandler(cfg)
+	if err != nil {
+		return err
+	}
+	c.handler.Store(&handler)
+	return nil
+}
+
+// Handle добавляет заголовки CORS и отвечает на предварительные запросы,
+// не передавая их дальше.
+func (c *CORS) Handle(ctx *fiber.Ctx) error {
+	return (*c.handler.Load())(ctx)
+}
+
+func newHandler(cfg Config) (handler fiber.Handler, err error) {
+	if !cfg.Enabled {
+		return func(c *fiber.Ctx) error { return c.Next() }, nil
+	}
+	if len(cfg.AllowOrigins) == 0 {
+		return nil, errors.New("allow_origins is required")
+	}
+
+	// Fiber сообщает о недопустимых правилах паникой
+	defer func() {
+		if r := recover(); r != nil {
+			handler, err = nil, errors.New(strings.TrimPrefix(fmt.Sprint(r), "[CORS] "))
+		}
+	}()
+	return fibercors.New(fibercors.Config{
+		AllowOrigins:     strings.Join(cfg.AllowOrigins, ","),
+		AllowMethods:     strings.ToUpper(strings.Join(cfg.AllowMethods, ",")),
+		AllowHeaders:     strings.Join(cfg.AllowHeaders, ","),
+		ExposeHeaders:    strings.Join(cfg.ExposeHeaders, ","),
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.MaxAge / time.Second),
+	}), nil
+}
@@ -0,0 +1,104 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func request(t *testing.T, app *fiber.App, method, origin string) *http.Response {
+	req := httptest.NewRequest(method, "/posts", nil)
+	req.Header.Set(fiber.HeaderOrigin, origin)
+	if method == http.MethodOptions {
+		req.Header.Set(fiber.HeaderAccessControlRequestMethod, http.MethodPost)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestCORS_Handle(t *testing.T) {
+	type want struct {
+		code   int
+		origin string
+	}
+
+	testCases := []struct {
+		name   string
+		cfg    Config
+		method string
+		origin string
+		want   want
+	}{
+		{
+			name:   "disabled",
+			cfg:    Config{AllowOrigins: []string{"https://blog.example.com"}},
+			method: http.MethodGet,
+			origin: "https://blog.example.com",
+			want:   want{code: http.StatusOK},
+		},
+		{
+			name:   "allowed_origin",
+			cfg:    Config{Enabled: true, AllowOrigins: []string{"https://blog.example.com"}},
+			method: http.MethodGet,
+			origin: "https://blog.example.com",
+			want:   want{code: http.StatusOK, origin: "https://blog.example.com"},
+		},
+		{
+			name:   "other_origin",
+			cfg:    Config{Enabled: true, AllowOrigins: []string{"https://blog.example.com"}},
+			method: http.MethodGet,
+			origin: "https://evil.example.com",
+			want:   want{code: http.StatusOK},
+		},
+		{
+			name:   "preflight",
+			cfg:    Config{Enabled: true, AllowOrigins: []string{"https://*.example.com"}},
+			method: http.MethodOptions,
+			origin: "https://blog.example.com",
+			want:   want{code: http.StatusNoContent, origin: "https://blog.example.com"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := New(tc.cfg)
+			require.NoError(t, err)
+			app := fiber.New()
+			app.Use(c.Handle)
+			app.All("/posts", func(c *fiber.Ctx) error { return nil })
+
+			resp := request(t, app, tc.method, tc.origin)
+			assert.Equal(t, tc.want.code, resp.StatusCode)
+			assert.Equal(t, tc.want.origin, resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+		})
+	}
+}
+
+func TestCORS_Update(t *testing.T) {
+	c, err := New(Config{})
+	require.NoError(t, err)
+	app := fiber.New()
+	app.Use(c.Handle)
+	app.Get("/posts", func(c *fiber.Ctx) error { return nil })
+
+	resp := request(t, app, http.MethodGet, "https://blog.example.com")
+	assert.Empty(t, resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+
+	require.NoError(t, c.Update(Config{Enabled: true, AllowOrigins: []string{"https://blog.example.com"}}))
+	resp = request(t, app, http.MethodGet, "https://blog.example.com")
+	assert.Equal(t, "https://blog.example.com", resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+
+	err = c.Update(Config{Enabled: true, AllowOrigins: []string{"*"}, AllowCredentials: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Insecure setup")
+	err = c.Update(Config{Enabled: true, AllowOrigins: []string{"blog.example.com/path"}})
+	require.Error(t, err)
+	resp = request(t, app, http.MethodGet, "https://blog.example.com")
+	assert.Equal(t, "https://blog.example.com", resp.Header.Get(fiber.HeaderAccessControlAllowOrigin), "rules are kept on error")
+}
@@ -0,0 +1,180 @@
+package dataset
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/pkg/errors"
+)
+
+// Форматы данных: JSON - объект {"posts": [...]}, как в
+// migrations/blog_data.json, JSON Lines - пост на строку.
+const (
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+
+	ContentTypeJSON  = "application/json"
+	ContentTypeJSONL = "application/x-ndjson"
+)
+
+var ErrUnknownFormat = errors.New("format should be json or jsonl")
+
+// ParseFormat проверяет формат; пустой означает JSON.
+func ParseFormat(format string) (string, error) {
+	switch format {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatJSONL:
+		return FormatJSONL, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// FormatOf определяет формат по расширению файла: .jsonl и .ndjson - JSON
+// Lines, остальные - JSON.
+func FormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	default:
+		return FormatJSON
+	}
+}
+
+func ContentType(format string) string {
+	if format == FormatJSONL {
+		return ContentTypeJSONL
+	}
+	return ContentTypeJSON
+}
+
+// Encoder пишет посты по одному, не собирая весь набор в памяти. Close
+// завершает JSON-документ и должен быть вызван после последнего поста.
+type Encoder struct {
+	w      io.Writer
+	format string
+	n      int
+}
+
+func NewEncoder(w io.Writer, format string) *Encoder {
+	return &Encoder{w: w, format: format}
+}
+
+func (e *Encoder) Encode(post models.PostRecordDTO) error {
+	data, err := json.Marshal(post)
+	if err != nil {
+		return errors.Wrapf(err, "encode post %d", post.ID)
+	}
+
+	var prefix string
+	if e.format == FormatJSON {
+		prefix = ",\n    "
+		if e.n == 0 {
+			prefix = "{\n  \"posts\": [\n    "
+		}
+	}
+	e.n++
+	if _, err := io.WriteString(e.w, prefix); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(data); err != nil {
+		return err
+	}
+	if e.format == FormatJSONL {
+		_, err = io.WriteString(e.w, "\n")
+	}
+	return err
+}
+
+func (e *Encoder) Close() error {
+	if e.format != FormatJSON {
+		return nil
+	}
+	end := "\n  ]\n}\n"
+	if e.n == 0 {
+		end = "{\n  \"posts\": []\n}\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// Decoder читает посты по одному. Next возвращает io.EOF после последнего
+// поста.
+type Decoder struct {
+	dec    *json.Decoder
+	format string
+	// started - прочитано начало массива posts, done - его конец.
+	started, done bool
+}
+
+func NewDecoder(r io.Reader, format string) *Decoder {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	return &Decoder{dec: dec, format: format}
+}
+
+func (d *Decoder) Next() (models.PostRecordDTO, error) {
+	var post models.PostRecordDTO
+	if d.format == FormatJSONL {
+		if err := d.dec.Decode(&post); err != nil {
+			if errors.Is(err, io.EOF) {
+				return post, io.EOF
+			}
+			return post, errors.Wrap(err, "decode post")
+		}
+		return post, nil
+	}
+
+	if !d.started {
+		if err := d.openPosts(); err != nil {
+			return post, err
+		}
+		d.started = true
+	}
+	if d.done || !d.dec.More() {
+		d.done = true
+		return post, io.EOF
+	}
+	if err := d.dec.Decode(&post); err != nil {
+		return post, errors.Wrap(err, "decode post")
+	}
+	return post, nil
+}
+
+// openPosts пропускает начало документа до массива posts.
+func (d *Decoder) openPosts() error {
+	if err := d.expect(json.Delim('{')); err != nil {
+		return err
+	}
+	for d.dec.More() {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return errors.Wrap(err, "decode dataset")
+		}
+		if key, _ := tok.(string); key == "posts" {
+			return d.expect(json.Delim('['))
+		}
+		// значение неизвестного ключа не нужно
+		var skip json.RawMessage
+		if err := d.dec.Decode(&skip); err != nil {
+			return errors.Wrap(err, "decode dataset")
+		}
+	}
+	d.done = true
+	return nil
+}
+
+func (d *Decoder) expect(delim json.Delim) error {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return errors.Wrap(err, "decode dataset")
+	}
+	if tok != delim {
+		return errors.Errorf("decode dataset: expected %s, got %v", delim, tok)
+	}
+	return nil
+}
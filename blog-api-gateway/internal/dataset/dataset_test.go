@@ -0,0 +1,128 @@
+package dataset
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeAll(t *testing.T, r io.Reader, format string) ([]models.PostRecordDTO, error) {
+	var posts []models.PostRecordDTO
+	dec := NewDecoder(r, format)
+	for {
+		post, err := dec.Next()
+		if err == io.EOF {
+			return posts, nil
+		}
+		if err != nil {
+			return posts, err
+		}
+		posts = append(posts, post)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	posts := []models.PostRecordDTO{
+		{ID: 1, Title: "A", Author: "X", Content: "text"},
+		{ID: 2, Title: "B", Author: "Y"},
+	}
+
+	testCases := []struct {
+		format string
+		posts  []models.PostRecordDTO
+		want   string
+	}{
+		{
+			format: FormatJSON,
+			posts:  posts,
+			want:   "{\n  \"posts\": [\n    {\"id\":1,\"title\":\"A\",\"author\":\"X\",\"content\":\"text\"},\n    {\"id\":2,\"title\":\"B\",\"author\":\"Y\",\"content\":\"\"}\n  ]\n}\n",
+		},
+		{
+			format: FormatJSON,
+			want:   "{\n  \"posts\": []\n}\n",
+		},
+		{
+			format: FormatJSONL,
+			posts:  posts,
+			want:   "{\"id\":1,\"title\":\"A\",\"author\":\"X\",\"content\":\"text\"}\n{\"id\":2,\"title\":\"B\",\"author\":\"Y\",\"content\":\"\"}\n",
+		},
+		{
+			format: FormatJSONL,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := NewEncoder(&buf, tc.format)
+			for _, post := range tc.posts {
+				require.NoError(t, enc.Encode(post))
+			}
+			require.NoError(t, enc.Close())
+			assert.Equal(t, tc.want, buf.String())
+
+			decoded, err := decodeAll(t, &buf, tc.format)
+			require.NoError(t, err)
+			assert.Equal(t, tc.posts, decoded)
+		})
+	}
+}
+
+func TestDecoder_SeedFile(t *testing.T) {
+	file, err := os.Open("../../migrations/blog_data.json")
+	require.NoError(t, err)
+	defer file.Close()
+
+	posts, err := decodeAll(t, file, FormatJSON)
+	require.NoError(t, err)
+	require.Len(t, posts, 100)
+	assert.Equal(t, "Title 22", posts[21].Title)
+}
+
+func TestDecoder_Errors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format string
+		input  string
+		// decoded - число постов, прочитанных до ошибки.
+		decoded int
+	}{
+		{name: "not_object", format: FormatJSON, input: `[]`},
+		{name: "posts_not_array", format: FormatJSON, input: `{"posts": {}}`},
+		{name: "unknown_field", format: FormatJSON, input: `{"posts": [{"title": "A", "tags": []}]}`},
+		{name: "truncated", format: FormatJSON, input: `{"posts": [{"title": "A"}, {"title"`, decoded: 1},
+		{name: "jsonl_broken_line", format: FormatJSONL, input: "{\"title\": \"A\"}\n{\"title\": 1}\n", decoded: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			posts, err := decodeAll(t, strings.NewReader(tc.input), tc.format)
+			assert.Error(t, err)
+			assert.Len(t, posts, tc.decoded)
+		})
+	}
+
+	posts, err := decodeAll(t, strings.NewReader(`{"version": 1}`), FormatJSON)
+	require.NoError(t, err, "document without posts is empty")
+	assert.Empty(t, posts)
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+
+	_, err = ParseFormat("csv")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	assert.Equal(t, FormatJSONL, FormatOf("backup.NDJSON"))
+	assert.Equal(t, FormatJSON, FormatOf("backup.json"))
+	assert.Equal(t, FormatJSON, FormatOf(""))
+}
@@ -0,0 +1,31 @@
+package deadline
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+// New ограничивает время обработки запроса timeout: контекст c.UserContext()
+// получает срок, по истечении которого usecase и репозиторий прекращают
+// работу с context.DeadlineExceeded. Не подходит для потоковых маршрутов.
+// При timeout <= 0 срок не устанавливается.
+func New(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if timeout <= 0 {
+			return c.Next()
+		}
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
+
+// IsContextError сообщает, прервана ли операция истечением срока или отменой
+// контекста запроса.
+func IsContextError(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,57 @@
+package deadline
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name    string
+		timeout time.Duration
+		want    bool
+	}{
+		{name: "with_timeout", timeout: time.Second, want: true},
+		{name: "disabled", timeout: 0, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				dl  time.Time
+				ok  bool
+				ctx context.Context
+			)
+			app := fiber.New()
+			app.Get("/", New(tc.timeout), func(c *fiber.Ctx) error {
+				ctx = c.UserContext()
+				dl, ok = ctx.Deadline()
+				return nil
+			})
+
+			_, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, ok)
+			if tc.want {
+				assert.WithinDuration(t, time.Now().Add(tc.timeout), dl, tc.timeout)
+				assert.ErrorIs(t, ctx.Err(), context.Canceled, "context is released after the handler")
+			}
+		})
+	}
+}
+
+func TestIsContextError(t *testing.T) {
+	assert.True(t, IsContextError(errors.Wrap(context.DeadlineExceeded, "get post")))
+	assert.True(t, IsContextError(context.Canceled))
+	assert.False(t, IsContextError(errors.New("boom")))
+	assert.False(t, IsContextError(nil))
+}
@@ -0,0 +1,205 @@
+package feature
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+	"github.com/pkg/errors"
+)
+
+const (
+	KeySubject = "subject"
+	KeyAPIKey  = "api_key"
+
+	// причины результата в журнале вычислений
+	reasonUnknown  = "unknown"
+	reasonDisabled = "disabled"
+	reasonEnabled  = "enabled"
+	reasonRollout  = "rollout"
+)
+
+type Config struct {
+	Flags []Flag `mapstructure:"flags" validate:"unique=Name,dive"`
+}
+
+// Flag включает возможность для всех клиентов или, если задан Percentage,
+// для доли клиентов в процентах. Клиент определяется по Key: subject -
+// аутентифицированный пользователь, api_key - ключ API; без них - по IP.
+// Клиент попадает в одну и ту же долю при каждом запросе.
+type Flag struct {
+	Name       string `mapstructure:"name" json:"name" validate:"required"`
+	Enabled    bool   `mapstructure:"enabled" json:"enabled"`
+	Percentage *int   `mapstructure:"percentage" json:"percentage,omitempty" validate:"omitempty,gte=0,lte=100"`
+	Key        string `mapstructure:"key" json:"key,omitempty" validate:"omitempty,oneof=subject api_key"`
+}
+
+// Flags хранит флаги и вычисляет их для запросов. Флаги меняются во время
+// работы.
+type Flags struct {
+	// mu упорядочивает изменения, чтение идет без блокировки
+	mu    sync.Mutex
+	flags atomic.Pointer[map[string]Flag]
+}
+
+func New(cfg Config) (*Flags, error) {
+	f := &Flags{}
+	if err := f.Update(cfg); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Update заменяет все флаги флагами из cfg, в том числе измененные через Set.
+// При ошибке флаги не меняются.
+func (f *Flags) Update(cfg Config) error {
+	flags := make(map[string]Flag, len(cfg.Flags))
+	for _, flag := range cfg.Flags {
+		if err := check(flag); err != nil {
+			return err
+		}
+		if _, ok := flags[flag.Name]; ok {
+			return errors.Errorf("flag %s: duplicate name", flag.Name)
+		}
+		flags[flag.Name] = flag
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flags.Store(&flags)
+	return nil
+}
+
+// Set добавляет или заменяет флаг.
+func (f *Flags) Set(flag Flag) error {
+	if err := check(flag); err != nil {
+		return err
+	}
+	f.modify(func(flags map[string]Flag) {
+		flags[flag.Name] = flag
+	})
+	return nil
+}
+
+// Delete удаляет флаг, после чего он считается выключенным.
+func (f *Flags) Delete(name string) {
+	f.modify(func(flags map[string]Flag) {
+		delete(flags, name)
+	})
+}
+
+func (f *Flags) modify(fn func(flags map[string]Flag)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current := *f.flags.Load()
+	flags := make(map[string]Flag, len(current)+1)
+	for name, flag := range current {
+		flags[name] = flag
+	}
+	fn(flags)
+	f.flags.Store(&flags)
+}
+
+func (f *Flags) Get(name string) (Flag, bool) {
+	flag, ok := (*f.flags.Load())[name]
+	return flag, ok
+}
+
+// List возвращает флаги, упорядоченные по имени.
+func (f *Flags) List() []Flag {
+	flags := make([]Flag, 0, len(*f.flags.Load()))
+	for _, flag := range *f.flags.Load() {
+		flags = append(flags, flag)
+	}
+	slices.SortFunc(flags, func(a, b Flag) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return flags
+}
+
+// Enabled вычисляет флаг name для запроса и записывает результат в журнал
+// на уровне debug. Неизвестный флаг выключен.
+func (f *Flags) Enabled(c *fiber.Ctx, name string) bool {
+	flag, ok := f.Get(name)
+	enabled, reason := false, reasonUnknown
+	if ok {
+		enabled, reason = flag.evaluate(c)
+	}
+	slog.DebugContext(c.UserContext(), "feature flag evaluated",
+		slog.String("flag", name),
+		slog.Bool("enabled", enabled),
+		slog.String("reason", reason))
+	return enabled
+}
+
+// Require пропускает запрос, если флаг name включен для клиента, иначе
+// отвечает 404, как если бы маршрута не было.
+func (f *Flags) Require(name string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !f.Enabled(c, name) {
+			return fiber.NewError(http.StatusNotFound)
+		}
+		return c.Next()
+	}
+}
+
+func (flag Flag) evaluate(c *fiber.Ctx) (bool, string) {
+	if !flag.Enabled {
+		return false, reasonDisabled
+	}
+	if flag.Percentage == nil {
+		return true, reasonEnabled
+	}
+	return bucket(flag.Name, clientKey(c, flag.Key)) < *flag.Percentage, reasonRollout
+}
+
+// bucket распределяет клиентов по 100 долям; у каждого флага свое
+// распределение, чтобы флаги с одинаковым процентом включались у разных
+// клиентов.
+func bucket(name, client string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name + "/" + client))
+	return int(h.Sum32() % 100)
+}
+
+// clientKey возвращает идентификатор клиента для флага. Без субъекта или
+// ключа API клиент определяется по IP. Ключ API используется только в виде
+// хеша.
+func clientKey(c *fiber.Ctx, key string) string {
+	switch key {
+	case KeySubject:
+		if id, ok := auth.FromContext(c); ok {
+			return "sub:" + id.Subject
+		}
+	case KeyAPIKey:
+		if apiKey := c.Get(auth.HeaderAPIKey); apiKey != "" {
+			sum := sha256.Sum256([]byte(apiKey))
+			return "key:" + hex.EncodeToString(sum[:16])
+		}
+	}
+	return "ip:" + c.IP()
+}
+
+func check(flag Flag) error {
+	if flag.Name == "" {
+		return errors.New("flag name is required")
+	}
+	if flag.Percentage != nil && (*flag.Percentage < 0 || *flag.Percentage > 100) {
+		return errors.Errorf("flag %s: percentage should be between 0 and 100", flag.Name)
+	}
+	switch flag.Key {
+	case "", KeySubject, KeyAPIKey:
+	default:
+		return errors.Errorf("flag %s: unknown key %q", flag.Name, flag.Key)
+	}
+	return nil
+}
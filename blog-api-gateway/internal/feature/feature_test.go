@@ -0,0 +1,117 @@
+package feature
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func percent(p int) *int {
+	return &p
+}
+
+// enabled вычисляет флаг name для запроса с ключом API apiKey.
+func enabled(t *testing.T, flags *Flags, name, apiKey string) bool {
+	var result bool
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		result = flags.Enabled(c, name)
+		return nil
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if apiKey != "" {
+		req.Header.Set(auth.HeaderAPIKey, apiKey)
+	}
+	_, err := app.Test(req)
+	require.NoError(t, err)
+	return result
+}
+
+func TestFlags_Enabled(t *testing.T) {
+	flags, err := New(Config{Flags: []Flag{
+		{Name: "search", Enabled: true},
+		{Name: "comments", Enabled: false},
+		{Name: "patch", Enabled: true, Percentage: percent(0), Key: KeyAPIKey},
+	}})
+	require.NoError(t, err)
+
+	assert.True(t, enabled(t, flags, "search", ""))
+	assert.False(t, enabled(t, flags, "comments", ""))
+	assert.False(t, enabled(t, flags, "patch", "key-1"))
+	assert.False(t, enabled(t, flags, "unknown", ""))
+}
+
+func TestFlags_Rollout(t *testing.T) {
+	flags, err := New(Config{Flags: []Flag{
+		{Name: "search", Enabled: true, Percentage: percent(30), Key: KeyAPIKey},
+	}})
+	require.NoError(t, err)
+
+	on := 0
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		result := enabled(t, flags, "search", key)
+		assert.Equal(t, result, enabled(t, flags, "search", key), "evaluation is stable per client")
+		if result {
+			on++
+		}
+	}
+	assert.InDelta(t, 300, on, 60)
+
+	require.NoError(t, flags.Set(Flag{Name: "search", Enabled: true, Percentage: percent(100), Key: KeyAPIKey}))
+	assert.True(t, enabled(t, flags, "search", "key-1"))
+}
+
+func TestFlags_Require(t *testing.T) {
+	flags, err := New(Config{Flags: []Flag{{Name: "search", Enabled: false}}})
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Get("/search", flags.Require("search"), func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+	do := func() int {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/search", nil))
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusNotFound, do())
+	require.NoError(t, flags.Set(Flag{Name: "search", Enabled: true}))
+	assert.Equal(t, http.StatusOK, do())
+	flags.Delete("search")
+	assert.Equal(t, http.StatusNotFound, do())
+}
+
+func TestFlags_Update(t *testing.T) {
+	flags, err := New(Config{Flags: []Flag{{Name: "search", Enabled: true}}})
+	require.NoError(t, err)
+	require.NoError(t, flags.Set(Flag{Name: "comments", Enabled: true}))
+
+	testCases := []struct {
+		name  string
+		flags []Flag
+	}{
+		{name: "no_name", flags: []Flag{{Enabled: true}}},
+		{name: "percentage", flags: []Flag{{Name: "a", Percentage: percent(101)}}},
+		{name: "key", flags: []Flag{{Name: "a", Key: "ip"}}},
+		{name: "duplicate", flags: []Flag{{Name: "a"}, {Name: "a"}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Error(t, flags.Update(Config{Flags: tc.flags}))
+		})
+	}
+	assert.Equal(t, []Flag{{Name: "comments", Enabled: true}, {Name: "search", Enabled: true}}, flags.List(),
+		"invalid config keeps the flags")
+
+	require.NoError(t, flags.Update(Config{Flags: []Flag{{Name: "patch"}}}))
+	assert.Equal(t, []Flag{{Name: "patch"}}, flags.List(), "update replaces flags set at runtime")
+}
@@ -0,0 +1,41 @@
+// Package featuretest включает и выключает флаги в тестах обработчиков.
+package featuretest
+
+import (
+	"testing"
+
+	"github.com/mtvy/blog-api-gateway/internal/feature"
+)
+
+// New возвращает флаги, включенные или выключенные для всех клиентов
+// согласно forced.
+func New(t testing.TB, forced map[string]bool) *feature.Flags {
+	t.Helper()
+
+	flags, err := feature.New(feature.Config{})
+	if err != nil {
+		t.Fatalf("create feature flags: %v", err)
+	}
+	for name, enabled := range forced {
+		Force(t, flags, name, enabled)
+	}
+	return flags
+}
+
+// Force включает или выключает флаг name для всех клиентов до конца теста,
+// после чего восстанавливает прежний флаг.
+func Force(t testing.TB, flags *feature.Flags, name string, enabled bool) {
+	t.Helper()
+
+	prev, existed := flags.Get(name)
+	if err := flags.Set(feature.Flag{Name: name, Enabled: enabled}); err != nil {
+		t.Fatalf("force feature flag %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		if existed {
+			_ = flags.Set(prev)
+			return
+		}
+		flags.Delete(name)
+	})
+}
@@ -0,0 +1,36 @@
+package featuretest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/feature"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForce(t *testing.T) {
+	flags, err := feature.New(feature.Config{Flags: []feature.Flag{{Name: "search", Enabled: false}}})
+	require.NoError(t, err)
+
+	t.Run("forced", func(t *testing.T) {
+		Force(t, flags, "search", true)
+		Force(t, flags, "comments", true)
+
+		app := fiber.New()
+		app.Get("/search", flags.Require("search"), func(c *fiber.Ctx) error {
+			return c.SendStatus(http.StatusOK)
+		})
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/search", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	assert.Equal(t, []feature.Flag{{Name: "search", Enabled: false}}, flags.List(), "flags are restored after the test")
+
+	forced := New(t, map[string]bool{"search": true, "comments": false})
+	assert.Equal(t, []feature.Flag{{Name: "comments", Enabled: false}, {Name: "search", Enabled: true}}, forced.List())
+}
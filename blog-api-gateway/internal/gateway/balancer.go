@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	RoundRobin       = "round_robin"
+	LeastConnections = "least_connections"
+	Weighted         = "weighted"
+	ConsistentHash   = "consistent_hash"
+)
+
+const hashReplicas = 100
+
+// balancer выбирает экземпляр из доступных. Список available не пуст
+// и сохраняет порядок экземпляров из конфигурации.
+type balancer interface {
+	next(c *fiber.Ctx, available []*Upstream) *Upstream
+}
+
+func newBalancer(route Route, upstreams []*Upstream) (balancer, error) {
+	switch route.Balancer {
+	case "", RoundRobin:
+		return &roundRobin{}, nil
+	case LeastConnections:
+		return leastConnections{}, nil
+	case Weighted:
+		return &weighted{current: make(map[*Upstream]int)}, nil
+	case ConsistentHash:
+		return newHashRing(route.HashHeader, upstreams), nil
+	}
+	return nil, errors.Errorf("unknown balancer %q", route.Balancer)
+}
+
+type roundRobin struct {
+	counter atomic.Uint64
+}
+
+func (b *roundRobin) next(_ *fiber.Ctx, available []*Upstream) *Upstream {
+	return available[(b.counter.Add(1)-1)%uint64(len(available))]
+}
+
+type leastConnections struct{}
+
+func (leastConnections) next(_ *fiber.Ctx, available []*Upstream) *Upstream {
+	best := available[0]
+	for _, u := range available[1:] {
+		if u.inFlight.Load() < best.inFlight.Load() {
+			best = u
+		}
+	}
+	return best
+}
+
+// weighted реализует плавный взвешенный round-robin (как в nginx).
+type weighted struct {
+	mu      sync.Mutex
+	current map[*Upstream]int
+}
+
+func (b *weighted) next(_ *fiber.Ctx, available []*Upstream) *Upstream {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var best *Upstream
+	total := 0
+	for _, u := range available {
+		b.current[u] += u.weight
+		total += u.weight
+		if best == nil || b.current[u] > b.current[best] {
+			best = u
+		}
+	}
+	b.current[best] -= total
+	return best
+}
+
+// hashRing выбирает экземпляр по хешу заголовка, так что запросы с одинаковым
+// значением попадают на один и тот же экземпляр, пока он доступен.
+// Без заголовка используется IP клиента.
+type hashRing struct {
+	header string
+	points []uint32
+	owners map[uint32]*Upstream
+}
+
+func newHashRing(header string, upstreams []*Upstream) *hashRing {
+	r := &hashRing{
+		header: header,
+		owners: make(map[uint32]*Upstream),
+	}
+	for _, u := range upstreams {
+		for i := 0; i < hashReplicas*u.weight; i++ {
+			p := hashKey(u.url.String() + "#" + strconv.Itoa(i))
+			r.points = append(r.points, p)
+			r.owners[p] = u
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+func (r *hashRing) next(c *fiber.Ctx, available []*Upstream) *Upstream {
+	key := c.IP()
+	if r.header != "" {
+		if v := c.Get(r.header); v != "" {
+			key = v
+		}
+	}
+
+	ok := make(map[*Upstream]bool, len(available))
+	for _, u := range available {
+		ok[u] = true
+	}
+
+	h := hashKey(key)
+	start := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	for i := 0; i < len(r.points); i++ {
+		u := r.owners[r.points[(start+i)%len(r.points)]]
+		if ok[u] {
+			return u
+		}
+	}
+	return available[0]
+}
+
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()
+}
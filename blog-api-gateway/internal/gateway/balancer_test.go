@@ -0,0 +1,148 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testUpstreams(t *testing.T, weights ...int) []*Upstream {
+	var upstreams []*Upstream
+	for i, w := range weights {
+		u, err := newUpstream(UpstreamConfig{URL: "http://upstream-" + string(rune('a'+i)), Weight: w})
+		require.NoError(t, err)
+		upstreams = append(upstreams, u)
+	}
+	return upstreams
+}
+
+func testCtx(header, value string) *fiber.Ctx {
+	app := fiber.New()
+	fctx := &fasthttp.RequestCtx{}
+	if header != "" {
+		fctx.Request.Header.Set(header, value)
+	}
+	return app.AcquireCtx(fctx)
+}
+
+func pickCounts(b balancer, c *fiber.Ctx, upstreams []*Upstream, n int) map[*Upstream]int {
+	counts := map[*Upstream]int{}
+	for i := 0; i < n; i++ {
+		counts[b.next(c, upstreams)]++
+	}
+	return counts
+}
+
+func TestBalancer_Weighted(t *testing.T) {
+	upstreams := testUpstreams(t, 3, 1)
+	b, err := newBalancer(Route{Balancer: Weighted}, upstreams)
+	require.NoError(t, err)
+
+	counts := pickCounts(b, testCtx("", ""), upstreams, 8)
+	assert.Equal(t, 6, counts[upstreams[0]])
+	assert.Equal(t, 2, counts[upstreams[1]])
+}
+
+func TestBalancer_LeastConnections(t *testing.T) {
+	upstreams := testUpstreams(t, 1, 1, 1)
+	upstreams[0].inFlight.Store(5)
+	upstreams[1].inFlight.Store(1)
+	upstreams[2].inFlight.Store(3)
+
+	b, err := newBalancer(Route{Balancer: LeastConnections}, upstreams)
+	require.NoError(t, err)
+	assert.Equal(t, upstreams[1], b.next(testCtx("", ""), upstreams))
+}
+
+func TestBalancer_ConsistentHash(t *testing.T) {
+	upstreams := testUpstreams(t, 1, 1, 1)
+	b, err := newBalancer(Route{Balancer: ConsistentHash, HashHeader: "X-User-ID"}, upstreams)
+	require.NoError(t, err)
+
+	c := testCtx("X-User-ID", "42")
+	first := b.next(c, upstreams)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, first, b.next(c, upstreams))
+	}
+
+	var rest []*Upstream
+	for _, u := range upstreams {
+		if u != first {
+			rest = append(rest, u)
+		}
+	}
+	assert.NotEqual(t, first, b.next(c, rest))
+
+	spread := map[*Upstream]bool{}
+	for _, id := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"} {
+		spread[b.next(testCtx("X-User-ID", id), upstreams)] = true
+	}
+	assert.Greater(t, len(spread), 1)
+}
+
+func TestProxy_PassiveEjection(t *testing.T) {
+	var badCalls atomic.Int32
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		badCalls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+	good := newTestUpstream(t, "good")
+
+	g, err := New(Config{Routes: []Route{{
+		Prefix:    "/users",
+		Upstreams: urls(bad.URL, good.URL),
+		Passive:   PassiveCheck{MaxFailures: 2, EjectDuration: time.Minute},
+	}}})
+	require.NoError(t, err)
+	app := fiber.New()
+	g.Mount(app)
+
+	for i := 0; i < 10; i++ {
+		do(t, app, httptest.NewRequest(http.MethodGet, "/users", nil))
+	}
+	assert.Equal(t, int32(2), badCalls.Load())
+
+	status := g.Status()[0].Upstreams
+	assert.False(t, status[0].Healthy)
+	assert.True(t, status[1].Healthy)
+}
+
+func TestGateway_HealthCheck(t *testing.T) {
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" && !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	g, err := New(Config{Routes: []Route{{
+		Prefix:      "/users",
+		Upstreams:   urls(srv.URL),
+		HealthCheck: HealthCheck{Path: "/healthz", Interval: 5 * time.Millisecond},
+	}}})
+	require.NoError(t, err)
+	app := fiber.New()
+	g.Mount(app)
+
+	g.Start()
+	defer g.Stop()
+
+	require.Eventually(t, func() bool { return !g.Status()[0].Upstreams[0].Healthy }, time.Second, time.Millisecond)
+	resp, _ := do(t, app, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	healthy.Store(true)
+	require.Eventually(t, func() bool { return g.Status()[0].Upstreams[0].Healthy }, time.Second, time.Millisecond)
+	resp, _ = do(t, app, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
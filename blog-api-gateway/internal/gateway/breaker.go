@@ -0,0 +1,149 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// BreakerConfig задает автомат размыкания. Нулевой FailureThreshold
+// отключает его.
+type BreakerConfig struct {
+	FailureThreshold int           `mapstructure:"failure_threshold" validate:"gte=0"`
+	OpenTimeout      time.Duration `mapstructure:"open_timeout" validate:"gte=0"`
+	HalfOpenRequests int           `mapstructure:"half_open_requests" validate:"gte=0"`
+}
+
+// breaker размыкается после FailureThreshold ошибок подряд, через OpenTimeout
+// пропускает HalfOpenRequests пробных запросов и замыкается, если все они
+// успешны, иначе снова размыкается. onChange вызывается при каждом переходе
+// под блокировкой автомата.
+type breaker struct {
+	cfg      BreakerConfig
+	onChange func(BreakerState)
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+}
+
+func newBreaker(cfg BreakerConfig, onChange func(BreakerState)) *breaker {
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	return &breaker{
+		cfg:      cfg,
+		onChange: onChange,
+		state:    BreakerClosed,
+	}
+}
+
+// allow возвращает false и время до следующей пробы, если запрос нужно отклонить.
+func (b *breaker) allow(now time.Time) (bool, time.Duration) {
+	if b.cfg.FailureThreshold <= 0 {
+		return true, 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		return true, 0
+	case BreakerOpen:
+		if wait := b.cfg.OpenTimeout - now.Sub(b.openedAt); wait > 0 {
+			return false, wait
+		}
+		b.set(BreakerHalfOpen)
+		b.probes = 0
+		b.successes = 0
+	}
+
+	if b.probes >= b.cfg.HalfOpenRequests {
+		return false, b.cfg.OpenTimeout
+	}
+	b.probes++
+	return true, 0
+}
+
+func (b *breaker) record(success bool, now time.Time) {
+	if b.cfg.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.open(now)
+		}
+	case BreakerHalfOpen:
+		if !success {
+			b.open(now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.set(BreakerClosed)
+			b.failures = 0
+		}
+	}
+}
+
+// release возвращает пробу, разрешенную allow, результат которой неизвестен:
+// запрос отменен клиентом или истек его срок.
+func (b *breaker) release() {
+	if b.cfg.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+func (b *breaker) open(now time.Time) {
+	b.set(BreakerOpen)
+	b.openedAt = now
+	b.failures = 0
+}
+
+func (b *breaker) set(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(state)
+	}
+}
+
+func (b *breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cfg.OpenTimeout {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
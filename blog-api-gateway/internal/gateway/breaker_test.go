@@ -0,0 +1,255 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/deadline"
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreaker_States(t *testing.T) {
+	b := newBreaker(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenRequests: 2}, nil)
+	now := time.Now()
+
+	b.record(false, now)
+	assert.Equal(t, BreakerClosed, b.State())
+	b.record(false, now)
+	assert.Equal(t, BreakerOpen, b.State())
+
+	ok, wait := b.allow(now.Add(time.Second))
+	assert.False(t, ok)
+	assert.Equal(t, 59*time.Second, wait)
+
+	later := now.Add(time.Minute)
+	ok, _ = b.allow(later)
+	assert.True(t, ok)
+	ok, _ = b.allow(later)
+	assert.True(t, ok)
+	ok, _ = b.allow(later)
+	assert.False(t, ok, "only half_open_requests probes are allowed")
+
+	b.record(true, later)
+	b.record(false, later)
+	ok, _ = b.allow(later.Add(time.Second))
+	assert.False(t, ok, "failed probe opens the breaker again")
+
+	later = later.Add(time.Minute)
+	b.allow(later)
+	b.allow(later)
+	b.record(true, later)
+	b.record(true, later)
+	assert.Equal(t, BreakerClosed, b.State())
+}
+
+func TestBreaker_Release(t *testing.T) {
+	b := newBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenRequests: 1}, nil)
+	now := time.Now()
+	b.record(false, now)
+
+	later := now.Add(time.Minute)
+	ok, _ := b.allow(later)
+	require.True(t, ok)
+	ok, _ = b.allow(later)
+	require.False(t, ok)
+
+	b.release()
+	ok, _ = b.allow(later)
+	assert.True(t, ok, "released probe slot can be used again")
+	b.record(true, later)
+	assert.Equal(t, BreakerClosed, b.State())
+}
+
+func TestGateway_ObserveBreakers(t *testing.T) {
+	breaker := BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}
+	g, err := New(Config{Routes: []Route{
+		{Prefix: "/users", Upstreams: urls("http://127.0.0.1:1"), Breaker: breaker},
+		{Prefix: "/posts", Upstreams: urls("http://127.0.0.1:1")},
+	}})
+	require.NoError(t, err)
+
+	states := make(map[string]BreakerState)
+	g.ObserveBreakers(func(prefix string, state BreakerState) {
+		states[prefix] = state
+	})
+	assert.Equal(t, map[string]BreakerState{"/users": BreakerClosed, "/posts": BreakerClosed}, states)
+
+	(*g.proxies.Load())[0].breaker.record(false, time.Now())
+	assert.Equal(t, BreakerOpen, states["/users"])
+
+	require.NoError(t, g.Update([]Route{
+		{Prefix: "/users", Upstreams: urls("http://127.0.0.1:1"), Breaker: breaker},
+		{Prefix: "/orders", Upstreams: urls("http://127.0.0.1:1")},
+	}))
+	assert.Equal(t, map[string]BreakerState{
+		"/users":  BreakerOpen,
+		"/posts":  "",
+		"/orders": BreakerClosed,
+	}, states, "unchanged routes keep their breaker, removed routes are reported empty")
+}
+
+func TestProxy_CanceledProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("slow") != "" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	g, err := New(Config{Routes: []Route{{
+		Prefix:    "/users",
+		Upstreams: urls(srv.URL),
+		Breaker:   BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Millisecond, HalfOpenRequests: 1},
+	}}})
+	require.NoError(t, err)
+	proxy := g.proxies.Load()
+	(*proxy)[0].breaker.record(false, time.Now())
+	time.Sleep(2 * time.Millisecond)
+
+	app := fiber.New()
+	app.Use(deadline.New(20 * time.Millisecond))
+	g.Mount(app)
+
+	resp, _ := do(t, app, httptest.NewRequest(http.MethodGet, "/users?slow=1", nil))
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode, "deadline error reaches the app error handler")
+	assert.Equal(t, BreakerHalfOpen, (*proxy)[0].breaker.State())
+
+	resp, _ = do(t, app, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "probe slot is released after the deadline")
+	assert.Equal(t, BreakerClosed, (*proxy)[0].breaker.State())
+}
+
+func TestProxy_RetryBackoffCanceled(t *testing.T) {
+	srv, calls := newFlakyUpstream(t, 100)
+	g, err := New(Config{RetryBudget: RetryBudget{MinPerSecond: 10}, Routes: []Route{{
+		Prefix:    "/users",
+		Upstreams: urls(srv.URL),
+		Retry:     RetryConfig{Attempts: 3, Backoff: time.Minute, MaxBackoff: time.Minute},
+	}}})
+	require.NoError(t, err)
+	app := fiber.New()
+	app.Use(deadline.New(50 * time.Millisecond))
+	g.Mount(app)
+
+	start := time.Now()
+	do(t, app, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Less(t, time.Since(start), time.Second, "backoff stops at the request deadline")
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRetryBudget(t *testing.T) {
+	b := newRetryBudget(RetryBudget{Ratio: 0.5})
+	assert.False(t, b.withdraw())
+
+	for i := 0; i < 4; i++ {
+		b.deposit()
+	}
+	assert.True(t, b.withdraw())
+	assert.True(t, b.withdraw())
+	assert.False(t, b.withdraw())
+
+	b = newRetryBudget(RetryBudget{MinPerSecond: 0.1})
+	assert.True(t, b.withdraw())
+	assert.False(t, b.withdraw())
+}
+
+func newFlakyUpstream(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestProxy_Retry(t *testing.T) {
+	testCases := []struct {
+		name      string
+		method    string
+		budget    RetryBudget
+		wantCode  int
+		wantCalls int32
+	}{
+		{
+			name:      "idempotent",
+			method:    http.MethodGet,
+			budget:    RetryBudget{MinPerSecond: 10},
+			wantCode:  http.StatusOK,
+			wantCalls: 3,
+		},
+		{
+			name:      "not_idempotent",
+			method:    http.MethodPost,
+			budget:    RetryBudget{MinPerSecond: 10},
+			wantCode:  http.StatusServiceUnavailable,
+			wantCalls: 1,
+		},
+		{
+			name:      "budget_exhausted",
+			method:    http.MethodGet,
+			budget:    RetryBudget{},
+			wantCode:  http.StatusServiceUnavailable,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, calls := newFlakyUpstream(t, 2)
+			g, err := New(Config{RetryBudget: tc.budget, Routes: []Route{{
+				Prefix:    "/users",
+				Upstreams: urls(srv.URL),
+				Retry:     RetryConfig{Attempts: 3, Backoff: time.Millisecond},
+			}}})
+			require.NoError(t, err)
+			app := fiber.New()
+			g.Mount(app)
+
+			resp, _ := do(t, app, httptest.NewRequest(tc.method, "/users", nil))
+			assert.Equal(t, tc.wantCode, resp.StatusCode)
+			assert.Equal(t, tc.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestProxy_CircuitOpen(t *testing.T) {
+	srv, calls := newFlakyUpstream(t, 100)
+
+	g, err := New(Config{Routes: []Route{{
+		Prefix:    "/users",
+		Upstreams: urls(srv.URL),
+		Breaker:   BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute},
+	}}})
+	require.NoError(t, err)
+
+	var lastErr error
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		lastErr = err
+		return fiber.DefaultErrorHandler(c, err)
+	}})
+	g.Mount(app)
+
+	for i := 0; i < 2; i++ {
+		resp, _ := do(t, app, httptest.NewRequest(http.MethodGet, "/users", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	resp, _ := do(t, app, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, "60", resp.Header.Get(fiber.HeaderRetryAfter))
+	assert.True(t, errors.Is(lastErr, apperr.ErrCircuitOpen))
+	assert.Equal(t, int32(2), calls.Load())
+
+	status := g.Status()[0]
+	assert.Equal(t, BreakerOpen, status.Breaker)
+	assert.Equal(t, uint64(1), status.Rejected)
+}
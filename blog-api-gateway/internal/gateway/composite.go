@@ -0,0 +1,361 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+	"github.com/mtvy/blog-api-gateway/internal/httpx"
+	"github.com/mtvy/blog-api-gateway/internal/tracing"
+	"github.com/pkg/errors"
+	"github.com/valyala/fasthttp"
+)
+
+// Composite описывает составной маршрут: части запрашиваются параллельно,
+// а их JSON-ответы объединяются в один ответ.
+type Composite struct {
+	Path    string        `mapstructure:"path" validate:"required,startswith=/"`
+	Timeout time.Duration `mapstructure:"timeout" validate:"gte=0"`
+	Parts   []Part        `mapstructure:"parts" validate:"required,dive"`
+}
+
+// Part - часть составного ответа. URL, начинающийся с /, обрабатывается
+// внутри шлюза (собственные обработчики и проксируемые маршруты), иначе
+// запрашивается напрямую. В URL подставляются параметры пути ({id}) и
+// поля ответов предыдущих частей ({post.Author}). Удаленной части
+// заголовки с учетными данными (Authorization, Cookie, X-API-Key) не
+// передаются, кроме перечисленных в ForwardHeaders.
+type Part struct {
+	Name           string        `mapstructure:"name" validate:"required"`
+	URL            string        `mapstructure:"url" validate:"required"`
+	Timeout        time.Duration `mapstructure:"timeout" validate:"gte=0"`
+	Required       bool          `mapstructure:"required"`
+	ForwardHeaders []string      `mapstructure:"forward_headers"`
+}
+
+type CompositeResponse struct {
+	Data    map[string]json.RawMessage `json:"data"`
+	Errors  []PartError                `json:"errors,omitempty"`
+	Partial bool                       `json:"partial"`
+}
+
+type PartError struct {
+	Part    string `json:"part"`
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+var placeholderRe = regexp.MustCompile(`\{([^{}]+)\}`)
+
+type partResult struct {
+	body json.RawMessage
+	err  *PartError
+}
+
+type compositeHandler struct {
+	cfg    Composite
+	deps   [][]int
+	client *fasthttp.Client
+
+	app         *fiber.App
+	handlerOnce sync.Once
+	handler     fasthttp.RequestHandler
+}
+
+func newComposite(cfg Composite) (*compositeHandler, error) {
+	if !strings.HasPrefix(cfg.Path, "/") {
+		return nil, errors.New("path should start with /")
+	}
+	if len(cfg.Parts) == 0 {
+		return nil, errors.New("no parts")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	params := pathParams(cfg.Path)
+	index := make(map[string]int, len(cfg.Parts))
+	deps := make([][]int, len(cfg.Parts))
+	for i, part := range cfg.Parts {
+		if part.Name == "" || strings.Contains(part.Name, ".") {
+			return nil, errors.Errorf("part %d: name should be set and must not contain .", i)
+		}
+		if _, ok := index[part.Name]; ok {
+			return nil, errors.Errorf("part %s: duplicate name", part.Name)
+		}
+		if !strings.HasPrefix(part.URL, "/") {
+			u, err := url.Parse(part.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return nil, errors.Errorf("part %s: url should start with / or http(s)://", part.Name)
+			}
+		}
+
+		for _, m := range placeholderRe.FindAllStringSubmatch(part.URL, -1) {
+			name, _, isField := strings.Cut(m[1], ".")
+			if !isField {
+				if !slices.Contains(params, name) {
+					return nil, errors.Errorf("part %s: unknown path param %s", part.Name, name)
+				}
+				continue
+			}
+			dep, ok := index[name]
+			if !ok {
+				return nil, errors.Errorf("part %s: depends on unknown or later part %s", part.Name, name)
+			}
+			deps[i] = append(deps[i], dep)
+		}
+		index[part.Name] = i
+	}
+
+	return &compositeHandler{
+		cfg:  cfg,
+		deps: deps,
+		client: &fasthttp.Client{
+			Name:                     "blog-api-gateway",
+			NoDefaultUserAgentHeader: true,
+		},
+	}, nil
+}
+
+// Handle запрашивает части параллельно; часть, зависящая от других, ждет их
+// ответов. Ошибка обязательной части возвращается клиентом целиком, ошибки
+// остальных попадают в errors ответа с признаком partial.
+func (h *compositeHandler) Handle(c *fiber.Ctx) error {
+	deadline := time.Now().Add(h.cfg.Timeout)
+	if dl, ok := c.UserContext().Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	results := make([]partResult, len(h.cfg.Parts))
+	done := make([]chan struct{}, len(h.cfg.Parts))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	params := make(map[string]string)
+	for _, name := range c.Route().Params {
+		params[name] = utils.CopyString(c.Params(name))
+	}
+	header := forwardedHeader(c)
+	remoteAddr := c.Context().RemoteAddr()
+	ctx := c.UserContext()
+
+	for i := range h.cfg.Parts {
+		go func(i int) {
+			defer close(done[i])
+			part := h.cfg.Parts[i]
+
+			for _, dep := range h.deps[i] {
+				<-done[dep]
+				if results[dep].err != nil {
+					results[i].err = &PartError{
+						Part:    part.Name,
+						Status:  http.StatusFailedDependency,
+						Message: fmt.Sprintf("depends on failed part %s", h.cfg.Parts[dep].Name),
+					}
+					return
+				}
+			}
+
+			target, err := h.expand(part.URL, params, results)
+			if err != nil {
+				results[i].err = &PartError{Part: part.Name, Status: http.StatusBadGateway, Message: err.Error()}
+				return
+			}
+
+			timeout := time.Until(deadline)
+			if part.Timeout > 0 && part.Timeout < timeout {
+				timeout = part.Timeout
+			}
+			results[i] = h.fetch(ctx, part, target, header, remoteAddr, timeout)
+		}(i)
+	}
+
+	resp := CompositeResponse{Data: make(map[string]json.RawMessage, len(h.cfg.Parts))}
+	for i, part := range h.cfg.Parts {
+		<-done[i]
+		if e := results[i].err; e != nil {
+			if part.Required {
+				return fiber.NewError(requiredStatus(e.Status), fmt.Sprintf("part %s: %s", e.Part, e.Message))
+			}
+			resp.Errors = append(resp.Errors, *e)
+			resp.Data[part.Name] = json.RawMessage("null")
+			continue
+		}
+		resp.Data[part.Name] = results[i].body
+	}
+	resp.Partial = len(resp.Errors) > 0
+
+	return c.JSON(resp)
+}
+
+func (h *compositeHandler) fetch(ctx context.Context, part Part, target string, header *fasthttp.RequestHeader, remoteAddr net.Addr, timeout time.Duration) partResult {
+	name := part.Name
+	var (
+		status int
+		body   []byte
+		err    error
+	)
+	if strings.HasPrefix(target, "/") {
+		status, body, err = h.fetchLocal(ctx, target, header, remoteAddr, timeout)
+	} else {
+		status, body, err = h.fetchRemote(target, header, part.ForwardHeaders, timeout)
+	}
+
+	switch {
+	case errors.Is(err, fasthttp.ErrTimeout):
+		return partResult{err: &PartError{Part: name, Status: http.StatusGatewayTimeout, Message: "timeout"}}
+	case err != nil:
+		return partResult{err: &PartError{Part: name, Status: http.StatusBadGateway, Message: err.Error()}}
+	case status < 200 || status >= 300:
+		return partResult{err: &PartError{Part: name, Status: status, Message: http.StatusText(status)}}
+	case !json.Valid(body):
+		return partResult{err: &PartError{Part: name, Status: http.StatusBadGateway, Message: "response is not valid json"}}
+	}
+	return partResult{body: body}
+}
+
+// userContextKey - ключ, под которым Fiber хранит c.UserContext() в
+// fasthttp.RequestCtx.
+const userContextKey = "__local_user_context__"
+
+// fetchLocal выполняет запрос через обработчик приложения без сетевого
+// вызова. Контекст запроса части получает срок timeout и отменяется при
+// выходе, так что обработчик, не успевший ответить, прекращает работу; его
+// ответ отбрасывается. Запрос помечается внутренним и не расходует лимит
+// запросов клиента.
+func (h *compositeHandler) fetchLocal(ctx context.Context, target string, header *fasthttp.RequestHeader, remoteAddr net.Addr, timeout time.Duration) (int, []byte, error) {
+	h.handlerOnce.Do(func() {
+		h.handler = h.app.Handler()
+	})
+
+	var req fasthttp.Request
+	header.CopyTo(&req.Header)
+	req.SetRequestURI(target)
+
+	ctx, cancel := context.WithTimeout(httpx.WithInternal(ctx), timeout)
+	defer cancel()
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Init(&req, remoteAddr, nil)
+	fctx.SetUserValue(userContextKey, ctx)
+
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		h.handler(fctx)
+	}()
+
+	select {
+	case <-finished:
+		return fctx.Response.StatusCode(), fctx.Response.Body(), nil
+	case <-ctx.Done():
+		return 0, nil, fasthttp.ErrTimeout
+	}
+}
+
+// fetchRemote запрашивает часть за пределами шлюза. Учетные данные клиента
+// передаются, только если они перечислены в forward.
+func (h *compositeHandler) fetchRemote(target string, header *fasthttp.RequestHeader, forward []string, timeout time.Duration) (int, []byte, error) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	header.CopyTo(&req.Header)
+	for _, h := range credentialHeaders {
+		if !slices.ContainsFunc(forward, func(name string) bool { return strings.EqualFold(name, h) }) {
+			req.Header.Del(h)
+		}
+	}
+	req.SetRequestURI(target)
+	req.Header.SetHost(string(req.URI().Host()))
+
+	if err := h.client.DoTimeout(req, resp, timeout); err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode(), append([]byte(nil), resp.Body()...), nil
+}
+
+// expand подставляет в шаблон параметры пути и поля ответов частей.
+func (h *compositeHandler) expand(tmpl string, params map[string]string, results []partResult) (string, error) {
+	var expandErr error
+	expanded := placeholderRe.ReplaceAllStringFunc(tmpl, func(m string) string {
+		key := m[1 : len(m)-1]
+		name, field, isField := strings.Cut(key, ".")
+		if !isField {
+			return url.PathEscape(params[name])
+		}
+
+		for i, part := range h.cfg.Parts {
+			if part.Name != name {
+				continue
+			}
+			var fields map[string]any
+			if err := json.Unmarshal(results[i].body, &fields); err != nil {
+				expandErr = errors.Errorf("part %s is not a json object", name)
+				return ""
+			}
+			value, ok := fields[field]
+			if !ok || value == nil {
+				expandErr = errors.Errorf("part %s has no field %s", name, field)
+				return ""
+			}
+			return url.PathEscape(fmt.Sprint(value))
+		}
+		return ""
+	})
+	return expanded, expandErr
+}
+
+// credentialHeaders содержат учетные данные клиента для шлюза и не
+// передаются сторонним сервисам без явного разрешения.
+var credentialHeaders = []string{
+	fiber.HeaderAuthorization,
+	fiber.HeaderCookie,
+	auth.HeaderAPIKey,
+}
+
+// forwardedHeader копирует заголовки исходного запроса для частей: метод
+// всегда GET, тело не передается, traceparent указывает на текущий span.
+func forwardedHeader(c *fiber.Ctx) *fasthttp.RequestHeader {
+	header := &fasthttp.RequestHeader{}
+	c.Request().Header.CopyTo(header)
+	for _, h := range hopHeaders {
+		header.Del(h)
+	}
+	header.SetMethod(http.MethodGet)
+	header.SetContentLength(0)
+	header.Del(fiber.HeaderContentType)
+	header.Del(fiber.HeaderAcceptEncoding)
+	tracing.Inject(c.UserContext(), header)
+	return header
+}
+
+// pathParams возвращает имена параметров шаблона пути Fiber.
+func pathParams(path string) []string {
+	var params []string
+	for _, segment := range strings.Split(path, "/") {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			params = append(params, strings.TrimSuffix(name, "?"))
+		}
+	}
+	return params
+}
+
+func requiredStatus(status int) int {
+	switch status {
+	case http.StatusNotFound, http.StatusGatewayTimeout, http.StatusUnauthorized, http.StatusForbidden:
+		return status
+	}
+	return http.StatusBadGateway
+}
@@ -0,0 +1,200 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/httpx"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCompositeApp(t *testing.T, commentsDelay time.Duration) *fiber.App {
+	users := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/alice" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"alice","api_key":"` + r.Header.Get("X-API-Key") +
+			`","authorization":"` + r.Header.Get("Authorization") + `","cookie":"` + r.Header.Get("Cookie") + `"}`))
+	}))
+	t.Cleanup(users.Close)
+
+	g, err := New(Config{Composites: []Composite{{
+		Path:    "/views/post/:id",
+		Timeout: time.Second,
+		Parts: []Part{
+			{Name: "post", URL: "/posts/{id}", Required: true},
+			{Name: "author", URL: users.URL + "/users/{post.Author}", ForwardHeaders: []string{"authorization"}},
+			{Name: "comments", URL: "/comments/count?post_id={id}", Timeout: 50 * time.Millisecond},
+		},
+	}}})
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Get("/posts/:id", func(c *fiber.Ctx) error {
+		switch c.Params("id") {
+		case "1":
+			return c.JSON(fiber.Map{"ID": 1, "Author": "alice"})
+		case "2":
+			return c.JSON(fiber.Map{"ID": 2, "Author": "bob"})
+		}
+		return fiber.NewError(http.StatusNotFound)
+	})
+	app.Get("/comments/count", func(c *fiber.Ctx) error {
+		time.Sleep(commentsDelay)
+		return c.JSON(fiber.Map{"count": 3, "post_id": c.Query("post_id")})
+	})
+	g.Mount(app)
+	return app
+}
+
+func TestComposite(t *testing.T) {
+	testCases := []struct {
+		name          string
+		url           string
+		commentsDelay time.Duration
+		wantCode      int
+		wantData      map[string]string
+		wantErrors    map[string]int
+	}{
+		{
+			name:     "all_parts",
+			url:      "/views/post/1",
+			wantCode: http.StatusOK,
+			wantData: map[string]string{
+				"post":     `{"Author":"alice","ID":1}`,
+				"author":   `{"name":"alice","api_key":"","authorization":"Bearer token","cookie":""}`,
+				"comments": `{"count":3,"post_id":"1"}`,
+			},
+		},
+		{
+			name:          "optional_part_timeout",
+			url:           "/views/post/1",
+			commentsDelay: 200 * time.Millisecond,
+			wantCode:      http.StatusOK,
+			wantData:      map[string]string{"comments": `null`},
+			wantErrors:    map[string]int{"comments": http.StatusGatewayTimeout},
+		},
+		{
+			name:       "optional_part_failed",
+			url:        "/views/post/2",
+			wantCode:   http.StatusOK,
+			wantData:   map[string]string{"author": `null`},
+			wantErrors: map[string]int{"author": http.StatusNotFound},
+		},
+		{
+			name:     "required_part_failed",
+			url:      "/views/post/3",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := newCompositeApp(t, tc.commentsDelay)
+
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			req.Header.Set("X-API-Key", "secret")
+			req.Header.Set("Authorization", "Bearer token")
+			req.Header.Set("Cookie", "session=1")
+			resp, body := do(t, app, req)
+			require.Equal(t, tc.wantCode, resp.StatusCode, body)
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+
+			var got CompositeResponse
+			require.NoError(t, json.Unmarshal([]byte(body), &got))
+			for part, want := range tc.wantData {
+				assert.JSONEq(t, want, string(got.Data[part]), part)
+			}
+			assert.Equal(t, len(tc.wantErrors) > 0, got.Partial)
+			assert.Len(t, got.Errors, len(tc.wantErrors))
+			for _, e := range got.Errors {
+				assert.Equal(t, tc.wantErrors[e.Part], e.Status, e.Part)
+			}
+		})
+	}
+}
+
+func TestComposite_DependencyFailed(t *testing.T) {
+	g, err := New(Config{Composites: []Composite{{
+		Path: "/views/post/:id",
+		Parts: []Part{
+			{Name: "post", URL: "/posts/{id}"},
+			{Name: "author", URL: "/users/{post.Author}"},
+		},
+	}}})
+	require.NoError(t, err)
+	app := fiber.New()
+	g.Mount(app)
+
+	_, body := do(t, app, httptest.NewRequest(http.MethodGet, "/views/post/1", nil))
+
+	var got CompositeResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &got))
+	require.Len(t, got.Errors, 2)
+	assert.Equal(t, http.StatusNotFound, got.Errors[0].Status)
+	assert.Equal(t, http.StatusFailedDependency, got.Errors[1].Status)
+}
+
+func TestComposite_LocalContext(t *testing.T) {
+	g, err := New(Config{Composites: []Composite{{
+		Path:    "/views/slow",
+		Timeout: 50 * time.Millisecond,
+		Parts:   []Part{{Name: "slow", URL: "/slow"}},
+	}}})
+	require.NoError(t, err)
+
+	internal := make(chan bool, 1)
+	released := make(chan struct{})
+	app := fiber.New()
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		internal <- httpx.Internal(c.UserContext())
+		<-c.UserContext().Done()
+		close(released)
+		return c.UserContext().Err()
+	})
+	g.Mount(app)
+
+	_, body := do(t, app, httptest.NewRequest(http.MethodGet, "/views/slow", nil))
+	var got CompositeResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &got))
+	require.Len(t, got.Errors, 1)
+	assert.Equal(t, http.StatusGatewayTimeout, got.Errors[0].Status)
+
+	assert.True(t, <-internal, "part requests are internal")
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("part handler is not cancelled after timeout")
+	}
+}
+
+func TestNew_InvalidComposite(t *testing.T) {
+	testCases := []struct {
+		name  string
+		parts []Part
+	}{
+		{name: "no_parts"},
+		{name: "unknown_param", parts: []Part{{Name: "post", URL: "/posts/{slug}"}}},
+		{name: "param_prefix", parts: []Part{{Name: "post", URL: "/posts/{i}"}}},
+		{name: "later_part", parts: []Part{{Name: "author", URL: "/users/{post.Author}"}, {Name: "post", URL: "/posts/{id}"}}},
+		{name: "duplicate", parts: []Part{{Name: "post", URL: "/posts/{id}"}, {Name: "post", URL: "/posts/{id}"}}},
+		{name: "bad_url", parts: []Part{{Name: "post", URL: "posts/{id}"}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(Config{Composites: []Composite{{Path: "/views/post/:id", Parts: tc.parts}}})
+			assert.Error(t, err)
+		})
+	}
+}
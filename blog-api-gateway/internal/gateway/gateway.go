@@ -0,0 +1,302 @@
+package gateway
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+type Config struct {
+	Routes      []Route     `mapstructure:"routes" validate:"dive"`
+	Composites  []Composite `mapstructure:"composites" validate:"dive"`
+	RetryBudget RetryBudget `mapstructure:"retry_budget"`
+}
+
+type Route struct {
+	Name        string           `mapstructure:"name"`
+	Prefix      string           `mapstructure:"prefix" validate:"required,startswith=/"`
+	Upstreams   []UpstreamConfig `mapstructure:"upstreams" validate:"required,dive"`
+	Balancer    string           `mapstructure:"balancer" validate:"omitempty,oneof=round_robin least_connections weighted consistent_hash"`
+	HashHeader  string           `mapstructure:"hash_header"`
+	HealthCheck HealthCheck      `mapstructure:"health_check"`
+	Passive     PassiveCheck     `mapstructure:"passive"`
+	Breaker     BreakerConfig    `mapstructure:"circuit_breaker"`
+	Retry       RetryConfig      `mapstructure:"retry"`
+	StripPrefix bool             `mapstructure:"strip_prefix"`
+	Timeout     time.Duration    `mapstructure:"timeout" validate:"gte=0"`
+	Request     HeaderRewrite    `mapstructure:"request_headers"`
+	Response    HeaderRewrite    `mapstructure:"response_headers"`
+}
+
+type HeaderRewrite struct {
+	Set    map[string]string `mapstructure:"set"`
+	Remove []string          `mapstructure:"remove"`
+}
+
+type RouteStatus struct {
+	Name          string           `json:"name"`
+	Prefix        string           `json:"prefix"`
+	Balancer      string           `json:"balancer"`
+	Breaker       BreakerState     `json:"circuit_breaker"`
+	Rejected      uint64           `json:"rejected"`
+	Retries       uint64           `json:"retries"`
+	RetriesDenied uint64           `json:"retries_denied"`
+	Upstreams     []UpstreamStatus `json:"upstreams"`
+}
+
+// BreakerObserver получает состояние автомата размыкания маршрута prefix при
+// каждом переходе; пустое состояние означает, что маршрут удален. Вызывается
+// под блокировкой автомата и не должен блокироваться.
+type BreakerObserver func(prefix string, state BreakerState)
+
+// Gateway проксирует запросы по префиксу пути на вышестоящие сервисы и
+// собирает составные ответы. Маршруты прокси заменяются через Update без
+// перезапуска.
+type Gateway struct {
+	budget     *retryBudget
+	proxies    atomic.Pointer[[]*Proxy]
+	composites []*compositeHandler
+	observer   atomic.Pointer[BreakerObserver]
+
+	// mu упорядочивает Start, Stop и Update; done закрывается при остановке
+	// проверок здоровья текущего набора маршрутов.
+	mu      sync.Mutex
+	started bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func New(cfg Config) (*Gateway, error) {
+	g := &Gateway{
+		budget: newRetryBudget(cfg.RetryBudget),
+	}
+	proxies, err := g.newProxies(cfg.Routes, nil)
+	if err != nil {
+		return nil, err
+	}
+	g.proxies.Store(&proxies)
+	for _, composite := range cfg.Composites {
+		h, err := newComposite(composite)
+		if err != nil {
+			return nil, errors.Wrapf(err, "composite %s", composite.Path)
+		}
+		g.composites = append(g.composites, h)
+	}
+	return g, nil
+}
+
+// newProxies создает прокси маршрутов. Прокси из current с той же
+// конфигурацией переиспользуются, сохраняя состояние автомата и здоровья
+// экземпляров.
+func (g *Gateway) newProxies(routes []Route, current []*Proxy) ([]*Proxy, error) {
+	proxies := make([]*Proxy, 0, len(routes))
+	for _, route := range routes {
+		prefix := route.Prefix
+		proxy, err := NewProxy(route, g.budget, func(state BreakerState) {
+			g.breakerChanged(prefix, state)
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "route %s", route.Prefix)
+		}
+		for _, p := range current {
+			if reflect.DeepEqual(p.route, proxy.route) {
+				proxy = p
+				break
+			}
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
+
+func (g *Gateway) routes() []*Proxy {
+	return *g.proxies.Load()
+}
+
+// ObserveBreakers передает observer текущее состояние автоматов всех
+// маршрутов и затем каждый их переход.
+func (g *Gateway) ObserveBreakers(observer BreakerObserver) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.observer.Store(&observer)
+	for _, p := range g.routes() {
+		observer(p.route.Prefix, p.breaker.State())
+	}
+}
+
+func (g *Gateway) breakerChanged(prefix string, state BreakerState) {
+	if observer := g.observer.Load(); observer != nil {
+		(*observer)(prefix, state)
+	}
+}
+
+// Update заменяет маршруты прокси. Запросы, уже переданные прокси, завершаются
+// по старым маршрутам. При ошибке маршруты не меняются.
+func (g *Gateway) Update(routes []Route) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	current := g.routes()
+	proxies, err := g.newProxies(routes, current)
+	if err != nil {
+		return err
+	}
+	g.proxies.Store(&proxies)
+	g.reportBreakers(current, proxies)
+	if g.started {
+		g.stopChecks()
+		g.startChecks(proxies)
+	}
+	return nil
+}
+
+// reportBreakers сообщает наблюдателю об удаленных маршрутах и о начальном
+// состоянии автоматов новых прокси.
+func (g *Gateway) reportBreakers(current, proxies []*Proxy) {
+	prefixes := make(map[string]bool, len(proxies))
+	for _, p := range proxies {
+		prefixes[p.route.Prefix] = true
+		if !slices.Contains(current, p) {
+			g.breakerChanged(p.route.Prefix, p.breaker.State())
+		}
+	}
+	for _, p := range current {
+		if !prefixes[p.route.Prefix] {
+			g.breakerChanged(p.route.Prefix, "")
+		}
+	}
+}
+
+// Mount регистрирует маршруты прокси (сам префикс и все пути под ним) и
+// составные маршруты. Части составных маршрутов вызывают обработчики app.
+// Маршруты, добавленные через Update, обслуживает обработчик, установленный
+// последним.
+func (g *Gateway) Mount(app *fiber.App) {
+	for _, h := range g.composites {
+		h.app = app
+		app.Get(h.cfg.Path, h.Handle)
+	}
+	for _, p := range g.routes() {
+		handle := g.handlePrefix(p.route.Prefix)
+		app.All(p.route.Prefix, handle)
+		app.All(p.route.Prefix+"/*", handle)
+	}
+	app.Use(g.dispatch)
+}
+
+// handlePrefix передает запрос текущему прокси с префиксом prefix. Если
+// маршрут удален, запрос проходит дальше.
+func (g *Gateway) handlePrefix(prefix string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, p := range g.routes() {
+			if p.route.Prefix == prefix {
+				return p.Handle(c)
+			}
+		}
+		return c.Next()
+	}
+}
+
+// dispatch передает запрос прокси с самым длинным подходящим префиксом.
+func (g *Gateway) dispatch(c *fiber.Ctx) error {
+	var match *Proxy
+	path := c.Path()
+	for _, p := range g.routes() {
+		if path != p.route.Prefix && !strings.HasPrefix(path, p.route.Prefix+"/") {
+			continue
+		}
+		if match == nil || len(p.route.Prefix) > len(match.route.Prefix) {
+			match = p
+		}
+	}
+	if match == nil {
+		return c.Next()
+	}
+	return match.Handle(c)
+}
+
+// Start запускает активные проверки здоровья экземпляров.
+func (g *Gateway) Start() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.started {
+		return
+	}
+	g.started = true
+	g.startChecks(g.routes())
+}
+
+func (g *Gateway) Stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.started = false
+	g.stopChecks()
+}
+
+func (g *Gateway) startChecks(proxies []*Proxy) {
+	done := make(chan struct{})
+	g.done = done
+	for _, p := range proxies {
+		if p.route.HealthCheck.Path == "" {
+			continue
+		}
+		g.wg.Add(1)
+		go func(p *Proxy) {
+			defer g.wg.Done()
+			p.runHealthChecks(done)
+		}(p)
+	}
+}
+
+func (g *Gateway) stopChecks() {
+	if g.done != nil {
+		close(g.done)
+		g.done = nil
+	}
+	g.wg.Wait()
+}
+
+// Ping возвращает ошибку, если у какого-либо маршрута не осталось исправных
+// экземпляров или разомкнут автомат.
+func (g *Gateway) Ping(ctx context.Context) error {
+	for _, p := range g.routes() {
+		if p.breaker.State() == BreakerOpen {
+			return errors.Errorf("route %s: circuit breaker is open", p.route.Prefix)
+		}
+		if len(p.available()) == 0 {
+			return errors.Errorf("route %s: no healthy upstream", p.route.Prefix)
+		}
+	}
+	return ctx.Err()
+}
+
+func (g *Gateway) Status() []RouteStatus {
+	proxies := g.routes()
+	statuses := make([]RouteStatus, 0, len(proxies))
+	for _, p := range proxies {
+		status := RouteStatus{
+			Name:          p.route.Name,
+			Prefix:        p.route.Prefix,
+			Balancer:      p.route.Balancer,
+			Breaker:       p.breaker.State(),
+			Rejected:      p.rejected.Load(),
+			Retries:       p.retries.Load(),
+			RetriesDenied: p.retriesDenied.Load(),
+		}
+		for _, u := range p.upstreams {
+			status.Upstreams = append(status.Upstreams, u.Status())
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
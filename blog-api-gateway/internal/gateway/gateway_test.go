@@ -0,0 +1,247 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUpstream(t *testing.T, name string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream", name)
+		w.Header().Set("X-Gateway-Seen", r.Header.Get("X-Gateway"))
+		w.Header().Set("X-Cookie-Seen", r.Header.Get("Cookie"))
+		w.Header().Set("X-API-Key-Seen", r.Header.Get("X-API-Key"))
+		w.Header().Set("X-Forwarded-For-Seen", r.Header.Get("X-Forwarded-For"))
+		w.Header().Set("Server", "upstream")
+		_, _ = io.WriteString(w, r.URL.RequestURI())
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func urls(raw ...string) []UpstreamConfig {
+	upstreams := make([]UpstreamConfig, 0, len(raw))
+	for _, u := range raw {
+		upstreams = append(upstreams, UpstreamConfig{URL: u})
+	}
+	return upstreams
+}
+
+func newTestApp(t *testing.T, routes ...Route) *fiber.App {
+	g, err := New(Config{Routes: routes})
+	require.NoError(t, err)
+
+	app := fiber.New()
+	g.Mount(app)
+	return app
+}
+
+func do(t *testing.T, app *fiber.App, req *http.Request) (*http.Response, string) {
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+func TestProxy_Path(t *testing.T) {
+	upstream := newTestUpstream(t, "a")
+
+	testCases := []struct {
+		name  string
+		route Route
+		url   string
+		want  string
+	}{
+		{
+			name:  "keep_prefix",
+			route: Route{Prefix: "/comments", Upstreams: urls(upstream.URL)},
+			url:   "/comments/1?sort=desc",
+			want:  "/comments/1?sort=desc",
+		},
+		{
+			name:  "strip_prefix",
+			route: Route{Prefix: "/comments", Upstreams: urls(upstream.URL), StripPrefix: true},
+			url:   "/comments/1?sort=desc",
+			want:  "/1?sort=desc",
+		},
+		{
+			name:  "strip_prefix_root",
+			route: Route{Prefix: "/comments", Upstreams: urls(upstream.URL), StripPrefix: true},
+			url:   "/comments",
+			want:  "/",
+		},
+		{
+			name:  "upstream_base_path",
+			route: Route{Prefix: "/media", Upstreams: urls(upstream.URL + "/api/v1/"), StripPrefix: true},
+			url:   "/media/images/2",
+			want:  "/api/v1/images/2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := newTestApp(t, tc.route)
+			resp, body := do(t, app, httptest.NewRequest(http.MethodGet, tc.url, nil))
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, tc.want, body)
+		})
+	}
+}
+
+func TestProxy_Headers(t *testing.T) {
+	upstream := newTestUpstream(t, "a")
+	app := newTestApp(t, Route{
+		Prefix:    "/users",
+		Upstreams: urls(upstream.URL),
+		Request: HeaderRewrite{
+			Set:    map[string]string{"X-Gateway": "blog"},
+			Remove: []string{"Cookie"},
+		},
+		Response: HeaderRewrite{
+			Remove: []string{"Server"},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("Cookie", "session=1")
+	req.Header.Set("X-API-Key", "secret")
+	resp, _ := do(t, app, req)
+
+	assert.Equal(t, "blog", resp.Header.Get("X-Gateway-Seen"))
+	assert.Empty(t, resp.Header.Get("X-Cookie-Seen"))
+	assert.Empty(t, resp.Header.Get("X-API-Key-Seen"), "gateway api key is not forwarded")
+	assert.NotEmpty(t, resp.Header.Get("X-Forwarded-For-Seen"))
+	assert.Empty(t, resp.Header.Get("Server"))
+}
+
+func TestProxy_Upstreams(t *testing.T) {
+	a, b := newTestUpstream(t, "a"), newTestUpstream(t, "b")
+	app := newTestApp(t, Route{Prefix: "/users", Upstreams: urls(a.URL, b.URL)})
+
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		resp, _ := do(t, app, httptest.NewRequest(http.MethodGet, "/users", nil))
+		seen[resp.Header.Get("X-Upstream")]++
+	}
+	assert.Equal(t, map[string]int{"a": 2, "b": 2}, seen)
+}
+
+func TestProxy_Errors(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	app := newTestApp(t,
+		Route{Prefix: "/slow", Upstreams: urls(slow.URL), Timeout: 20 * time.Millisecond},
+		Route{Prefix: "/dead", Upstreams: urls(dead.URL)},
+	)
+
+	resp, _ := do(t, app, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+
+	resp, _ = do(t, app, httptest.NewRequest(http.MethodGet, "/dead", nil))
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+}
+
+func TestProxy_RequestDeadline(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	g, err := New(Config{Routes: []Route{{
+		Prefix:    "/slow",
+		Upstreams: urls(slow.URL),
+		Timeout:   time.Second,
+		Breaker:   BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute},
+	}}})
+	require.NoError(t, err)
+
+	var lastErr error
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		lastErr = err
+		return fiber.DefaultErrorHandler(c, err)
+	}})
+	app.Use(func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), 20*time.Millisecond)
+		defer cancel()
+		c.SetUserContext(ctx)
+		return c.Next()
+	})
+	g.Mount(app)
+
+	start := time.Now()
+	_, _ = do(t, app, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Less(t, time.Since(start), 150*time.Millisecond)
+	assert.ErrorIs(t, lastErr, context.DeadlineExceeded)
+
+	status := g.Status()[0]
+	assert.Equal(t, BreakerClosed, status.Breaker)
+	assert.Zero(t, status.Upstreams[0].Failures)
+}
+
+func TestNew_InvalidRoute(t *testing.T) {
+	_, err := New(Config{Routes: []Route{{Prefix: "comments", Upstreams: urls("http://localhost")}}})
+	assert.Error(t, err)
+
+	_, err = New(Config{Routes: []Route{{Prefix: "/comments", Upstreams: urls("ftp://localhost")}}})
+	assert.Error(t, err)
+
+	_, err = New(Config{Routes: []Route{{Prefix: "/comments"}}})
+	assert.Error(t, err)
+
+	_, err = New(Config{Routes: []Route{{Prefix: "/comments", Upstreams: urls("http://localhost"), Balancer: "random"}}})
+	assert.Error(t, err)
+}
+
+func TestGateway_Update(t *testing.T) {
+	a := newTestUpstream(t, "a")
+	b := newTestUpstream(t, "b")
+
+	g, err := New(Config{Routes: []Route{{Prefix: "/comments", Upstreams: urls(a.URL)}}})
+	require.NoError(t, err)
+	app := fiber.New()
+	g.Mount(app)
+
+	upstream := func(path string) string {
+		resp, _ := do(t, app, httptest.NewRequest(http.MethodGet, path, nil))
+		if resp.StatusCode != http.StatusOK {
+			return resp.Status
+		}
+		return resp.Header.Get("X-Upstream")
+	}
+	assert.Equal(t, "a", upstream("/comments/1"))
+
+	proxy := g.routes()[0]
+	require.NoError(t, g.Update([]Route{
+		{Prefix: "/comments", Upstreams: urls(a.URL)},
+		{Prefix: "/likes", Upstreams: urls(b.URL)},
+		{Prefix: "/likes/top", Upstreams: urls(a.URL)},
+	}))
+	assert.Same(t, proxy, g.routes()[0], "unchanged route keeps its state")
+	assert.Equal(t, "a", upstream("/comments/1"))
+	assert.Equal(t, "b", upstream("/likes"))
+	assert.Equal(t, "b", upstream("/likes/1"))
+	assert.Equal(t, "a", upstream("/likes/top/1"))
+
+	require.Error(t, g.Update([]Route{{Prefix: "/likes"}}))
+	assert.Equal(t, "b", upstream("/likes/1"), "invalid routes are not applied")
+
+	require.NoError(t, g.Update([]Route{{Prefix: "/likes", Upstreams: urls(b.URL)}}))
+	assert.Equal(t, "404 Not Found", upstream("/comments/1"))
+	assert.Equal(t, "b", upstream("/likes/1"))
+}
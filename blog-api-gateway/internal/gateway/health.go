@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/valyala/fasthttp"
+)
+
+// HealthCheck включает активную проверку экземпляров, если задан Path.
+type HealthCheck struct {
+	Path     string        `mapstructure:"path" validate:"omitempty,startswith=/"`
+	Interval time.Duration `mapstructure:"interval" validate:"gte=0"`
+	Timeout  time.Duration `mapstructure:"timeout" validate:"gte=0"`
+}
+
+// PassiveCheck извлекает экземпляр на EjectDuration после MaxFailures
+// ошибок подряд. Нулевой MaxFailures отключает проверку.
+type PassiveCheck struct {
+	MaxFailures   int           `mapstructure:"max_failures" validate:"gte=0"`
+	EjectDuration time.Duration `mapstructure:"eject_duration" validate:"gte=0"`
+}
+
+func (p *Proxy) runHealthChecks(done <-chan struct{}) {
+	check := p.route.HealthCheck
+	if check.Interval <= 0 {
+		check.Interval = 10 * time.Second
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = time.Second
+	}
+
+	ticker := time.NewTicker(check.Interval)
+	defer ticker.Stop()
+
+	for {
+		for _, u := range p.upstreams {
+			u.setProbe(p.probe(u, check))
+		}
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Proxy) probe(u *Upstream, check HealthCheck) error {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(u.url.String() + check.Path)
+	req.Header.SetMethod(fasthttp.MethodGet)
+
+	if err := p.client.DoTimeout(req, resp, check.Timeout); err != nil {
+		return errors.Wrap(err, "health check")
+	}
+	if code := resp.StatusCode(); code < 200 || code >= 300 {
+		return errors.Errorf("health check: status code %d", code)
+	}
+	return nil
+}
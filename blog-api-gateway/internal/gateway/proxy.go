@@ -0,0 +1,301 @@
+package gateway
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+	"github.com/mtvy/blog-api-gateway/internal/deadline"
+	"github.com/mtvy/blog-api-gateway/internal/tracing"
+	"github.com/pkg/errors"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultTimeout = 30 * time.Second
+
+var tracer = otel.Tracer("github.com/mtvy/blog-api-gateway/internal/gateway")
+
+// hopHeaders не передаются между клиентом и вышестоящим сервисом (RFC 7230, 6.1).
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+type Proxy struct {
+	route     Route
+	upstreams []*Upstream
+	balancer  balancer
+	breaker   *breaker
+	budget    *retryBudget
+	client    *fasthttp.Client
+
+	rejected      atomic.Uint64
+	retries       atomic.Uint64
+	retriesDenied atomic.Uint64
+}
+
+// NewProxy создает прокси маршрута. onBreaker, если задан, получает каждое
+// новое состояние автомата размыкания.
+func NewProxy(route Route, budget *retryBudget, onBreaker func(BreakerState)) (*Proxy, error) {
+	if !strings.HasPrefix(route.Prefix, "/") || strings.HasSuffix(route.Prefix, "/") {
+		return nil, errors.New("prefix should start and must not end with /")
+	}
+	if len(route.Upstreams) == 0 {
+		return nil, errors.New("no upstreams")
+	}
+	upstreams := make([]*Upstream, 0, len(route.Upstreams))
+	for _, cfg := range route.Upstreams {
+		u, err := newUpstream(cfg)
+		if err != nil {
+			return nil, err
+		}
+		upstreams = append(upstreams, u)
+	}
+	if route.Balancer == "" {
+		route.Balancer = RoundRobin
+	}
+	b, err := newBalancer(route, upstreams)
+	if err != nil {
+		return nil, err
+	}
+	if route.Timeout <= 0 {
+		route.Timeout = defaultTimeout
+	}
+
+	return &Proxy{
+		route:     route,
+		upstreams: upstreams,
+		balancer:  b,
+		breaker:   newBreaker(route.Breaker, onBreaker),
+		budget:    budget,
+		client: &fasthttp.Client{
+			Name:                     "blog-api-gateway",
+			NoDefaultUserAgentHeader: true,
+			DisablePathNormalizing:   true,
+			ReadTimeout:              route.Timeout,
+			WriteTimeout:             route.Timeout,
+		},
+	}, nil
+}
+
+func (p *Proxy) Route() Route {
+	return p.route
+}
+
+// Handle проксирует запрос. Идемпотентные запросы повторяются при ошибках
+// транспорта и ответах 502-504, пока позволяют настройки маршрута, автомат
+// размыкания и общий бюджет повторов.
+func (p *Proxy) Handle(c *fiber.Ctx) error {
+	if ok, wait := p.breaker.allow(time.Now()); !ok {
+		p.rejected.Add(1)
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return errors.Wrapf(apperr.ErrCircuitOpen, "route %s", p.route.Prefix)
+	}
+	p.budget.deposit()
+
+	attempts := 1
+	if idempotent(c.Method()) {
+		attempts += p.route.Retry.Attempts
+	}
+
+	ctx := c.UserContext()
+	for attempt := 1; ; attempt++ {
+		status, err := p.forward(c)
+		if deadline.IsContextError(err) {
+			p.breaker.release()
+			return err
+		}
+		p.breaker.record(err == nil && status < http.StatusInternalServerError, time.Now())
+
+		if attempt >= attempts || !retryable(status, err) {
+			return err
+		}
+		if ok, _ := p.breaker.allow(time.Now()); !ok {
+			return err
+		}
+		if !p.budget.withdraw() {
+			p.retriesDenied.Add(1)
+			return err
+		}
+		p.retries.Add(1)
+		if err := sleep(ctx, p.route.Retry.backoff(attempt)); err != nil {
+			p.breaker.release()
+			return err
+		}
+	}
+}
+
+// sleep ждет d или отмены ctx.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Proxy) forward(c *fiber.Ctx) (status int, err error) {
+	available := p.available()
+	if len(available) == 0 {
+		return 0, fiber.NewError(http.StatusServiceUnavailable, "no healthy upstream")
+	}
+	target := p.balancer.next(c, available)
+
+	ctx, span := tracer.Start(c.UserContext(), "proxy "+p.route.Prefix,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(c.Method()),
+			semconv.ServerAddress(target.url.Host),
+		),
+	)
+	defer func() {
+		spanErr := err
+		if spanErr == nil && status >= http.StatusInternalServerError {
+			spanErr = errors.Errorf("status code %d", status)
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		tracing.End(span, spanErr)
+	}()
+
+	// срок запроса сокращает таймаут маршрута; его истечение не считается
+	// отказом апстрима
+	timeout, capped := p.route.Timeout, false
+	if dl, ok := ctx.Deadline(); ok {
+		left := time.Until(dl)
+		if left <= 0 {
+			return 0, context.DeadlineExceeded
+		}
+		if left < timeout {
+			timeout, capped = left, true
+		}
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	c.Request().CopyTo(req)
+
+	p.prepareRequest(c, req, target.url)
+	tracing.Inject(ctx, &req.Header)
+
+	target.inFlight.Add(1)
+	resp := c.Response()
+	err = p.client.DoTimeout(req, resp, timeout)
+	target.inFlight.Add(-1)
+	if capped && errors.Is(err, fasthttp.ErrTimeout) {
+		resp.Reset()
+		return 0, context.DeadlineExceeded
+	}
+
+	if err == nil && resp.StatusCode() >= http.StatusInternalServerError {
+		target.observe(errors.Errorf("status code %d", resp.StatusCode()), p.route.Passive)
+	} else {
+		target.observe(err, p.route.Passive)
+	}
+	if err != nil {
+		resp.Reset()
+		return 0, upstreamError(err)
+	}
+
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+	rewrite(&resp.Header, p.route.Response)
+	return resp.StatusCode(), nil
+}
+
+func retryable(status int, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (p *Proxy) available() []*Upstream {
+	now := time.Now()
+	available := make([]*Upstream, 0, len(p.upstreams))
+	for _, u := range p.upstreams {
+		if u.Healthy(now) {
+			available = append(available, u)
+		}
+	}
+	return available
+}
+
+func (p *Proxy) prepareRequest(c *fiber.Ctx, req *fasthttp.Request, target *url.URL) {
+	path := c.Path()
+	if p.route.StripPrefix {
+		path = strings.TrimPrefix(path, p.route.Prefix)
+		if path == "" {
+			path = "/"
+		}
+	}
+
+	uri := req.URI()
+	uri.SetScheme(target.Scheme)
+	uri.SetHost(target.Host)
+	uri.SetPath(target.Path + path)
+	uri.SetQueryStringBytes(c.Request().URI().QueryString())
+	req.Header.SetHost(target.Host)
+
+	for _, h := range hopHeaders {
+		req.Header.Del(h)
+	}
+	// ключ API проверен шлюзом и апстриму не предназначен
+	req.Header.Del(auth.HeaderAPIKey)
+
+	if prior := c.Get(fiber.HeaderXForwardedFor); prior != "" {
+		req.Header.Set(fiber.HeaderXForwardedFor, prior+", "+c.IP())
+	} else {
+		req.Header.Set(fiber.HeaderXForwardedFor, c.IP())
+	}
+	req.Header.Set(fiber.HeaderXForwardedHost, c.Hostname())
+	req.Header.Set(fiber.HeaderXForwardedProto, c.Protocol())
+
+	rewrite(&req.Header, p.route.Request)
+}
+
+type headerSetter interface {
+	Set(key, value string)
+	Del(key string)
+}
+
+func rewrite(h headerSetter, rw HeaderRewrite) {
+	for _, key := range rw.Remove {
+		h.Del(key)
+	}
+	for key, value := range rw.Set {
+		h.Set(key, value)
+	}
+}
+
+func upstreamError(err error) error {
+	if errors.Is(err, fasthttp.ErrTimeout) {
+		return fiber.NewError(http.StatusGatewayTimeout, "upstream timeout")
+	}
+	return fiber.NewError(http.StatusBadGateway, "upstream unavailable")
+}
@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryConfig задает число повторов после первой попытки. Повторяются
+// только идемпотентные методы.
+type RetryConfig struct {
+	Attempts   int           `mapstructure:"attempts" validate:"gte=0"`
+	Backoff    time.Duration `mapstructure:"backoff" validate:"gte=0"`
+	MaxBackoff time.Duration `mapstructure:"max_backoff" validate:"gte=0"`
+}
+
+// RetryBudget ограничивает долю повторов от всех запросов через шлюз,
+// чтобы повторы не умножали нагрузку на деградирующий сервис. MinPerSecond
+// повторов разрешено всегда.
+type RetryBudget struct {
+	Ratio        float64 `mapstructure:"ratio" validate:"gte=0"`
+	MinPerSecond float64 `mapstructure:"min_per_second" validate:"gte=0"`
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// backoff возвращает задержку с полным джиттером для повтора attempt (с 1).
+func (r RetryConfig) backoff(attempt int) time.Duration {
+	delay := r.Backoff << (attempt - 1)
+	if delay <= 0 || (r.MaxBackoff > 0 && delay > r.MaxBackoff) {
+		delay = r.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return rand.N(delay + 1)
+}
+
+const retryBudgetWindow = 10
+
+// retryBudget считает запросы и повторы в скользящем окне из
+// retryBudgetWindow секундных интервалов и разрешает повтор, пока повторов
+// меньше Ratio от запросов плюс MinPerSecond за каждую секунду окна.
+type retryBudget struct {
+	cfg RetryBudget
+
+	mu      sync.Mutex
+	buckets [retryBudgetWindow]budgetBucket
+}
+
+type budgetBucket struct {
+	second   int64
+	requests int
+	retries  int
+}
+
+func newRetryBudget(cfg RetryBudget) *retryBudget {
+	return &retryBudget{cfg: cfg}
+}
+
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bucket(time.Now()).requests++
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	requests, retries := 0, 0
+	for i := range b.buckets {
+		if now.Unix()-b.buckets[i].second < retryBudgetWindow {
+			requests += b.buckets[i].requests
+			retries += b.buckets[i].retries
+		}
+	}
+
+	allowed := b.cfg.Ratio*float64(requests) + b.cfg.MinPerSecond*retryBudgetWindow
+	if float64(retries) >= allowed {
+		return false
+	}
+	b.bucket(now).retries++
+	return true
+}
+
+func (b *retryBudget) bucket(now time.Time) *budgetBucket {
+	sec := now.Unix()
+	bucket := &b.buckets[sec%retryBudgetWindow]
+	if bucket.second != sec {
+		*bucket = budgetBucket{second: sec}
+	}
+	return bucket
+}
@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type UpstreamConfig struct {
+	URL    string `mapstructure:"url" validate:"required,url"`
+	Weight int    `mapstructure:"weight" validate:"gte=0"`
+}
+
+type UpstreamStatus struct {
+	URL          string    `json:"url"`
+	Weight       int       `json:"weight"`
+	Healthy      bool      `json:"healthy"`
+	ProbeHealthy bool      `json:"probe_healthy"`
+	EjectedUntil time.Time `json:"ejected_until,omitempty"`
+	Failures     int       `json:"consecutive_failures"`
+	InFlight     int64     `json:"in_flight"`
+	LastError    string    `json:"last_error,omitempty"`
+	LastCheck    time.Time `json:"last_check,omitempty"`
+}
+
+// Upstream хранит состояние экземпляра вышестоящего сервиса. Экземпляр
+// исключается из балансировки, если активная проверка неуспешна или он
+// временно извлечен пассивной проверкой после серии ошибок.
+type Upstream struct {
+	url      *url.URL
+	weight   int
+	inFlight atomic.Int64
+
+	mu           sync.Mutex
+	probeHealthy bool
+	failures     int
+	ejectedUntil time.Time
+	lastError    string
+	lastCheck    time.Time
+}
+
+func newUpstream(cfg UpstreamConfig) (*Upstream, error) {
+	parsed, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse upstream %s", cfg.URL)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, errors.Errorf("upstream %s: scheme should be http or https", cfg.URL)
+	}
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+
+	weight := cfg.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+	return &Upstream{
+		url:          parsed,
+		weight:       weight,
+		probeHealthy: true,
+	}, nil
+}
+
+func (u *Upstream) Healthy(now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.probeHealthy && !now.Before(u.ejectedUntil)
+}
+
+func (u *Upstream) Status() UpstreamStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return UpstreamStatus{
+		URL:          u.url.String(),
+		Weight:       u.weight,
+		Healthy:      u.probeHealthy && !time.Now().Before(u.ejectedUntil),
+		ProbeHealthy: u.probeHealthy,
+		EjectedUntil: u.ejectedUntil,
+		Failures:     u.failures,
+		InFlight:     u.inFlight.Load(),
+		LastError:    u.lastError,
+		LastCheck:    u.lastCheck,
+	}
+}
+
+// observe учитывает результат проксированного запроса для пассивной проверки.
+func (u *Upstream) observe(err error, passive PassiveCheck) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if err == nil {
+		u.failures = 0
+		return
+	}
+
+	u.failures++
+	u.lastError = err.Error()
+	if passive.MaxFailures > 0 && u.failures >= passive.MaxFailures {
+		u.ejectedUntil = time.Now().Add(passive.EjectDuration)
+		u.failures = 0
+	}
+}
+
+func (u *Upstream) setProbe(err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.lastCheck = time.Now()
+	u.probeHealthy = err == nil
+	if err != nil {
+		u.lastError = err.Error()
+	}
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/deadline"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/internal/validator"
+	"github.com/pkg/errors"
+)
+
+type apiKeysProvider interface {
+	ListAPIKeys(ctx context.Context) ([]models.APIKeyDTO, error)
+	IssueAPIKey(ctx context.Context, key models.APIKeyDTO) (*models.IssuedAPIKeyDTO, error)
+	RotateAPIKey(ctx context.Context, id uint64) (*models.IssuedAPIKeyDTO, error)
+	RevokeAPIKey(ctx context.Context, id uint64) error
+}
+
+type APIKeyHandle struct {
+	apiKeysUC apiKeysProvider
+}
+
+func NewAPIKey(apiKeysUC apiKeysProvider) *APIKeyHandle {
+	return &APIKeyHandle{
+		apiKeysUC: apiKeysUC,
+	}
+}
+
+// ListAPIKeys возвращает список ключей API без самих ключей.
+//
+//	@Summary		Список ключей API
+//	@Description	Получить выпущенные ключи API с правами, сроком действия и временем последнего использования
+//	@Tags			admin
+//	@Success		200	{object}	map[string][]models.APIKeyDTO
+//	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Router			/admin/api-keys [get]
+func (h *APIKeyHandle) ListAPIKeys(c *fiber.Ctx) error {
+	keys, err := h.apiKeysUC.ListAPIKeys(c.UserContext())
+	if err != nil {
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "list api keys", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(fiber.Map{"api_keys": keys})
+}
+
+// IssueAPIKey выпускает ключ API. Ключ возвращается только в ответе на выпуск.
+//
+//	@Summary		Выпустить ключ API
+//	@Description	Выпустить ключ API с правами posts:read, posts:write или admin
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			key	body		models.CreateAPIKeyRequest	true	"Данные ключа"
+//	@Success		200	{object}	models.IssuedAPIKeyDTO
+//	@Failure		400	{object}	map[string]interface{}	"Ошибка валидации"
+//	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Router			/admin/api-keys [post]
+func (h *APIKeyHandle) IssueAPIKey(c *fiber.Ctx) error {
+	req := &models.CreateAPIKeyRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.Validate(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	key, err := h.apiKeysUC.IssueAPIKey(c.UserContext(), req.ToDTO())
+	if err != nil {
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "issue api key", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(key)
+}
+
+// RotateAPIKey заменяет ключ API новым, прежний перестает действовать.
+//
+//	@Summary		Ротация ключа API
+//	@Description	Выпустить новый ключ с теми же правами вместо прежнего
+//	@Tags			admin
+//	@Param			id	path		int	true	"ID ключа"
+//	@Success		200	{object}	models.IssuedAPIKeyDTO
+//	@Failure		400	{object}	map[string]interface{}	"Некорректный ID"
+//	@Failure		404	{object}	map[string]interface{}	"Ключ не найден"
+//	@Failure		409	{object}	map[string]interface{}	"Ключ отозван"
+//	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Router			/admin/api-keys/{id}/rotate [post]
+func (h *APIKeyHandle) RotateAPIKey(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "id should be uint")
+	}
+
+	key, err := h.apiKeysUC.RotateAPIKey(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return fiber.NewError(http.StatusNotFound)
+		}
+		if errors.Is(err, apperr.ErrRevoked) {
+			return fiber.NewError(http.StatusConflict, "api key is revoked")
+		}
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "rotate api key", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(key)
+}
+
+// RevokeAPIKey отзывает ключ API.
+//
+//	@Summary		Отозвать ключ API
+//	@Description	Отозвать ключ API по идентификатору
+//	@Tags			admin
+//	@Param			id	path	int	true	"ID ключа"
+//	@Success		200	"Ключ отозван"
+//	@Failure		400	{object}	map[string]interface{}	"Некорректный ID"
+//	@Failure		404	{object}	map[string]interface{}	"Ключ не найден"
+//	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Router			/admin/api-keys/{id} [delete]
+func (h *APIKeyHandle) RevokeAPIKey(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "id should be uint")
+	}
+
+	if err := h.apiKeysUC.RevokeAPIKey(c.UserContext(), id); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return fiber.NewError(http.StatusNotFound)
+		}
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "revoke api key", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return c.SendStatus(http.StatusOK)
+}
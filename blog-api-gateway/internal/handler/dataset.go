@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/dataset"
+	"github.com/mtvy/blog-api-gateway/internal/deadline"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/internal/validator"
+	"github.com/pkg/errors"
+	"github.com/valyala/fasthttp"
+)
+
+type datasetProvider interface {
+	CountPosts() int
+	Export(ctx context.Context, send func(models.PostRecordDTO) error) error
+	Import(ctx context.Context, next func() (models.PostRecordDTO, error), mode models.ImportMode, progress func(models.ImportReportDTO)) (models.ImportReportDTO, error)
+	Seed(ctx context.Context, next func() (models.PostRecordDTO, error)) (int, error)
+}
+
+type DatasetHandle struct {
+	datasetUC datasetProvider
+}
+
+func NewDataset(datasetUC datasetProvider) *DatasetHandle {
+	return &DatasetHandle{
+		datasetUC: datasetUC,
+	}
+}
+
+// Export отдает все посты потоком в формате начальных данных или JSON Lines.
+// X-Total-Count - число постов на момент начала экспорта.
+//
+//	@Summary		Экспорт данных
+//	@Description	Получить все посты в формате начальных данных (migrations/blog_data.json) или JSON Lines. X-Total-Count - число постов
+//	@Tags			admin
+//	@Produce		json
+//	@Produce		application/x-ndjson
+//	@Param			format	query		string	false	"Формат"	Enums(json, jsonl)
+//	@Success		200		{object}	models.DatasetDTO
+//	@Failure		400		{object}	map[string]interface{}	"Неизвестный формат"
+//	@Failure		500		{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Failure		504		{object}	map[string]interface{}	"Превышено время обработки запроса"
+//	@Router			/admin/export [get]
+func (h *DatasetHandle) Export(c *fiber.Ctx) error {
+	format, err := dataset.ParseFormat(c.Query("format"))
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, dataset.ContentType(format))
+	c.Set("X-Total-Count", strconv.Itoa(h.datasetUC.CountPosts()))
+	ctx := c.UserContext()
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		enc := dataset.NewEncoder(w, format)
+		if err := h.datasetUC.Export(ctx, enc.Encode); err != nil {
+			slog.ErrorContext(ctx, "export", slog.Any("error", err))
+			return
+		}
+		if err := enc.Close(); err != nil {
+			slog.ErrorContext(ctx, "export", slog.Any("error", err))
+		}
+	}))
+	return nil
+}
+
+// Import сохраняет посты по мере чтения тела запроса; mode определяет, что
+// делать с занятыми идентификаторами. Если клиент принимает
+// application/x-ndjson, ход импорта отдается потоком строк
+// ImportProgressDTO, последняя из которых содержит итог.
+//
+//	@Summary		Импорт данных
+//	@Description	Сохранить посты в формате экспорта. Каждый пост проверяется до сохранения. mode: fail - при занятом идентификаторе ничего не сохраняется, skip - существующий пост остается, overwrite - заменяется, remap - все посты получают новые идентификаторы. Формат JSON Lines задается format=jsonl или Content-Type application/x-ndjson. С Accept: application/x-ndjson ход импорта отдается потоком строк ImportProgressDTO
+//	@Tags			admin
+//	@Accept			json
+//	@Accept			application/x-ndjson
+//	@Produce		json
+//	@Produce		application/x-ndjson
+//	@Param			data	body		models.DatasetDTO	true	"Данные в формате экспорта"
+//	@Param			mode	query		string				false	"Режим"	Enums(fail, skip, overwrite, remap)
+//	@Param			format	query		string				false	"Формат"	Enums(json, jsonl)
+//	@Success		200		{object}	models.ImportReportDTO
+//	@Failure		400		{object}	map[string]interface{}	"Ошибка валидации"
+//	@Failure		409		{object}	map[string]interface{}	"Идентификатор занят"
+//	@Failure		500		{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Router			/admin/import [post]
+func (h *DatasetHandle) Import(c *fiber.Ctx) error {
+	mode := models.ImportMode(c.Query("mode", string(models.ImportFail)))
+	switch mode {
+	case models.ImportFail, models.ImportSkip, models.ImportOverwrite, models.ImportRemap:
+	default:
+		return fiber.NewError(http.StatusBadRequest, "mode should be fail, skip, overwrite or remap")
+	}
+	next, err := newPostReader(c, mode != models.ImportRemap)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.UserContext()
+	if !strings.Contains(c.Get(fiber.HeaderAccept), dataset.ContentTypeJSONL) {
+		report, err := h.datasetUC.Import(ctx, next, mode, func(r models.ImportReportDTO) {
+			slog.InfoContext(ctx, "import progress", slog.Int("processed", r.Processed))
+		})
+		if err != nil {
+			if deadline.IsContextError(err) {
+				return err
+			}
+			return fiber.NewError(importError(ctx, err, report))
+		}
+		logImport(ctx, mode, report)
+		return c.JSON(report)
+	}
+
+	c.Set(fiber.HeaderContentType, dataset.ContentTypeJSONL)
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set("X-Accel-Buffering", "no")
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		enc := json.NewEncoder(w)
+		send := func(p models.ImportProgressDTO) {
+			if enc.Encode(p) == nil {
+				_ = w.Flush()
+			}
+		}
+
+		report, err := h.datasetUC.Import(ctx, next, mode, func(r models.ImportReportDTO) {
+			r.Remapped = nil
+			send(models.ImportProgressDTO{Status: models.ImportRunning, ImportReportDTO: r})
+		})
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if err != nil {
+			code, msg := importError(ctx, err, report)
+			report.Remapped = nil
+			send(models.ImportProgressDTO{Status: models.ImportFailed, ImportReportDTO: report, Code: code, Error: msg})
+			return
+		}
+		logImport(ctx, mode, report)
+		send(models.ImportProgressDTO{Status: models.ImportDone, ImportReportDTO: report})
+	}))
+	return nil
+}
+
+// importError возвращает код и сообщение для ошибки импорта.
+func importError(ctx context.Context, err error, report models.ImportReportDTO) (int, string) {
+	var fe *fiber.Error
+	switch {
+	case errors.As(err, &fe):
+		return fe.Code, fe.Message
+	case errors.Is(err, apperr.ErrConflict):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, err.Error()
+	}
+	slog.ErrorContext(ctx, "import", slog.Any("error", err), slog.Int("processed", report.Processed))
+	return http.StatusInternalServerError, utils.StatusMessage(http.StatusInternalServerError)
+}
+
+func logImport(ctx context.Context, mode models.ImportMode, report models.ImportReportDTO) {
+	slog.InfoContext(ctx, "import complete",
+		slog.String("mode", string(mode)),
+		slog.Int("created", report.Created),
+		slog.Int("updated", report.Updated),
+		slog.Int("skipped", report.Skipped))
+}
+
+// Seed добавляет посты с новыми идентификаторами.
+//
+//	@Summary		Наполнение данными
+//	@Description	Добавить посты в формате начальных данных или JSON Lines; идентификаторы из файла не используются
+//	@Tags			admin
+//	@Accept			json
+//	@Accept			application/x-ndjson
+//	@Produce		json
+//	@Param			data	body		models.DatasetDTO	true	"Данные в формате начальных данных"
+//	@Param			format	query		string				false	"Формат"	Enums(json, jsonl)
+//	@Success		200		{object}	map[string]int		"Число добавленных постов"
+//	@Failure		400		{object}	map[string]interface{}	"Ошибка валидации"
+//	@Failure		500		{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Failure		504		{object}	map[string]interface{}	"Превышено время обработки запроса"
+//	@Router			/admin/seed [post]
+func (h *DatasetHandle) Seed(c *fiber.Ctx) error {
+	next, err := newPostReader(c, false)
+	if err != nil {
+		return err
+	}
+
+	n, err := h.datasetUC.Seed(c.UserContext(), next)
+	if err != nil {
+		var fe *fiber.Error
+		if errors.As(err, &fe) || deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "seed", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(fiber.Map{"seeded": n})
+}
+
+// newPostReader возвращает функцию, которая читает и проверяет посты из тела
+// запроса по одному, не загружая его целиком. Формат JSON Lines задается
+// format=jsonl или Content-Type application/x-ndjson. При withIDs каждый пост
+// должен иметь идентификатор. Ошибка указывает номер поста, начиная с 1.
+func newPostReader(c *fiber.Ctx, withIDs bool) (func() (models.PostRecordDTO, error), error) {
+	format := c.Query("format")
+	if format == "" && strings.HasPrefix(c.Get(fiber.HeaderContentType), dataset.ContentTypeJSONL) {
+		format = dataset.FormatJSONL
+	}
+	format, err := dataset.ParseFormat(format)
+	if err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	body := c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+	dec := dataset.NewDecoder(body, format)
+	n := 0
+	return func() (models.PostRecordDTO, error) {
+		n++
+		post, err := dec.Next()
+		if errors.Is(err, io.EOF) {
+			return post, io.EOF
+		}
+		if err != nil {
+			return post, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("post %d: %s", n, err))
+		}
+		if err := validator.Validate(post); err != nil {
+			return post, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("post %d: %s", n, err))
+		}
+		if withIDs && post.ID == 0 {
+			return post, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("post %d: id is required", n))
+		}
+		return post, nil
+	}, nil
+}
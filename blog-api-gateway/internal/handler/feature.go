@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/mtvy/blog-api-gateway/internal/feature"
+)
+
+type featureFlags interface {
+	List() []feature.Flag
+	Set(flag feature.Flag) error
+	Delete(name string)
+}
+
+// FeatureHandle управляет флагами на служебном порту admin.port. Изменения
+// действуют до перезапуска или изменения features в конфигурации.
+type FeatureHandle struct {
+	flags featureFlags
+}
+
+func NewFeature(flags featureFlags) *FeatureHandle {
+	return &FeatureHandle{
+		flags: flags,
+	}
+}
+
+// ListFlags возвращает флаги.
+func (h *FeatureHandle) ListFlags(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"flags": h.flags.List()})
+}
+
+// SetFlag добавляет или заменяет флаг с именем из пути.
+func (h *FeatureHandle) SetFlag(c *fiber.Ctx) error {
+	flag := feature.Flag{}
+	if err := c.BodyParser(&flag); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	// имя хранится дольше запроса, а Params ссылается на буфер fasthttp
+	flag.Name = utils.CopyString(c.Params("name"))
+	if err := h.flags.Set(flag); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	slog.InfoContext(c.UserContext(), "feature flag set",
+		slog.String("flag", flag.Name),
+		slog.Bool("enabled", flag.Enabled),
+		slog.Any("percentage", flag.Percentage))
+
+	return c.JSON(flag)
+}
+
+// DeleteFlag удаляет флаг, после чего он выключен.
+func (h *FeatureHandle) DeleteFlag(c *fiber.Ctx) error {
+	h.flags.Delete(c.Params("name"))
+	slog.InfoContext(c.UserContext(), "feature flag deleted", slog.String("flag", c.Params("name")))
+
+	return c.SendStatus(http.StatusOK)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/feature"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFeatureHandle_SetFlag отправляет запросы через одно соединение:
+// fasthttp переиспользует его буферы, и имя флага, взятое из пути без
+// копирования, испортилось бы следующими запросами.
+func TestFeatureHandle_SetFlag(t *testing.T) {
+	flags, err := feature.New(feature.Config{})
+	require.NoError(t, err)
+	h := NewFeature(flags)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/flags", h.ListFlags)
+	app.Put("/flags/:name", h.SetFlag)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	client := &http.Client{Transport: &http.Transport{MaxConnsPerHost: 1}}
+	base := "http://" + ln.Addr().String()
+	for _, name := range []string{"search", "comments", "patch-posts"} {
+		req, err := http.NewRequest(http.MethodPut, base+"/flags/"+name, strings.NewReader(`{"enabled":true}`))
+		require.NoError(t, err)
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	// запросы с другими путями перезаписывают буфер соединения
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(fmt.Sprintf("%s/flags?padding=%s", base, strings.Repeat("x", 64)))
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	var names []string
+	for _, flag := range flags.List() {
+		names = append(names, flag.Name)
+	}
+	assert.Equal(t, []string{"comments", "patch-posts", "search"}, names)
+	_, ok := flags.Get("search")
+	assert.True(t, ok)
+}
@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/deadline"
 	"github.com/mtvy/blog-api-gateway/internal/models"
 	"github.com/mtvy/blog-api-gateway/internal/validator"
 	"github.com/pkg/errors"
@@ -15,11 +17,11 @@ import (
 )
 
 type postsProvider interface {
-	ListPost() ([]models.PostDTO, error)
-	GetPost(id uint64) (*models.PostDTO, error)
-	CreatePost(post models.PostDTO) (uint64, error)
-	UpdatePost(post models.PostDTO) error
-	DeletePost(id uint64) error
+	ListPost(ctx context.Context) ([]models.PostDTO, error)
+	GetPost(ctx context.Context, id uint64) (*models.PostDTO, error)
+	CreatePost(ctx context.Context, post models.PostDTO) (uint64, error)
+	UpdatePost(ctx context.Context, post models.PostDTO) error
+	DeletePost(ctx context.Context, id uint64) error
 }
 
 type Handle struct {
@@ -42,6 +44,7 @@ func New(postsUC postsProvider) *Handle {
 //	@Failure		400	{object}	map[string]interface{}	"Некорректный ID"
 //	@Failure		404	{object}	map[string]interface{}	"Пост не найден"
 //	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Failure		504	{object}	map[string]interface{}	"Превышено время обработки запроса"
 //	@Router			/posts/{id} [get]
 func (h *Handle) GetPost(c *fiber.Ctx) error {
 	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
@@ -49,12 +52,15 @@ func (h *Handle) GetPost(c *fiber.Ctx) error {
 		return fiber.NewError(http.StatusBadRequest, "id should be uint")
 	}
 
-	post, err := h.postsUC.GetPost(id)
+	post, err := h.postsUC.GetPost(c.UserContext(), id)
 	if err != nil {
 		if errors.Is(err, apperr.ErrNotFound) {
 			return fiber.NewError(http.StatusNotFound)
 		}
-		slog.Error("get post", slog.Any("error", err))
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "get post", slog.Any("error", err))
 		return fiber.NewError(http.StatusInternalServerError)
 	}
 
@@ -68,11 +74,15 @@ func (h *Handle) GetPost(c *fiber.Ctx) error {
 //	@Tags			posts
 //	@Success		200	{object}	map[string][]models.PostDTO
 //	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Failure		504	{object}	map[string]interface{}	"Превышено время обработки запроса"
 //	@Router			/posts [get]
 func (h *Handle) ListPost(c *fiber.Ctx) error {
-	posts, err := h.postsUC.ListPost()
+	posts, err := h.postsUC.ListPost(c.UserContext())
 	if err != nil {
-		slog.Error("get post", slog.Any("error", err))
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "get post", slog.Any("error", err))
 		return fiber.NewError(http.StatusInternalServerError, err.Error())
 	}
 
@@ -82,14 +92,18 @@ func (h *Handle) ListPost(c *fiber.Ctx) error {
 // CreatePost создает новый пост.
 //
 //	@Summary		Создать пост
-//	@Description	Создать новый пост
+//	@Description	Создать новый пост. Повтор с тем же Idempotency-Key возвращает первый ответ
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			post	body		models.CreatePostRequest	true	"Данные поста"
-//	@Success		200		{object}	map[string]uint64			"ID созданного поста"
-//	@Failure		400		{object}	map[string]interface{}		"Ошибка валидации"
-//	@Failure		500		{object}	map[string]interface{}		"Внутренняя ошибка сервера"
+//	@Param			post			body		models.CreatePostRequest	true	"Данные поста"
+//	@Param			Idempotency-Key	header		string						false	"Ключ идемпотентности"
+//	@Success		200				{object}	map[string]uint64			"ID созданного поста"
+//	@Failure		400				{object}	map[string]interface{}		"Ошибка валидации"
+//	@Failure		409				{object}	map[string]interface{}		"Запрос с этим ключом еще выполняется"
+//	@Failure		422				{object}	map[string]interface{}		"Ключ использован с другим телом запроса"
+//	@Failure		500				{object}	map[string]interface{}		"Внутренняя ошибка сервера"
+//	@Failure		504				{object}	map[string]interface{}		"Превышено время обработки запроса"
 //	@Router			/posts [post]
 func (h *Handle) CreatePost(c *fiber.Ctx) error {
 	req := &models.CreatePostRequest{}
@@ -101,9 +115,12 @@ func (h *Handle) CreatePost(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	id, err := h.postsUC.CreatePost(req.ToDTO())
+	id, err := h.postsUC.CreatePost(c.UserContext(), req.ToDTO())
 	if err != nil {
-		slog.Error("create post", slog.Any("error", err))
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "create post", slog.Any("error", err))
 		return fiber.NewError(http.StatusInternalServerError)
 	}
 
@@ -122,6 +139,7 @@ func (h *Handle) CreatePost(c *fiber.Ctx) error {
 //	@Failure		400		{object}	map[string]interface{}		"Ошибка валидации"
 //	@Failure		404		{object}	map[string]interface{}		"Пост не найден"
 //	@Failure		500		{object}	map[string]interface{}		"Внутренняя ошибка сервера"
+//	@Failure		504		{object}	map[string]interface{}		"Превышено время обработки запроса"
 //	@Router			/posts [put]
 func (h *Handle) UpdatePost(c *fiber.Ctx) error {
 	req := &models.UpdatePostRequest{}
@@ -134,11 +152,14 @@ func (h *Handle) UpdatePost(c *fiber.Ctx) error {
 	}
 
 	post := req.ToDTO()
-	if err := h.postsUC.UpdatePost(post); err != nil {
+	if err := h.postsUC.UpdatePost(c.UserContext(), post); err != nil {
 		if errors.Is(err, apperr.ErrNotFound) {
 			return fiber.NewError(http.StatusNotFound)
 		}
-		slog.Error("update post", slog.Any("error", err))
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "update post", slog.Any("error", err))
 		return fiber.NewError(http.StatusInternalServerError)
 	}
 
@@ -155,6 +176,7 @@ func (h *Handle) UpdatePost(c *fiber.Ctx) error {
 //	@Failure		400	{object}	map[string]interface{}	"Некорректный ID"
 //	@Failure		404	{object}	map[string]interface{}	"Пост не найден"
 //	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Failure		504	{object}	map[string]interface{}	"Превышено время обработки запроса"
 //	@Router			/posts/{id} [delete]
 func (h *Handle) DeletePost(c *fiber.Ctx) error {
 	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
@@ -162,11 +184,14 @@ func (h *Handle) DeletePost(c *fiber.Ctx) error {
 		return fiber.NewError(http.StatusBadRequest, "id should be uint")
 	}
 
-	if err := h.postsUC.DeletePost(id); err != nil {
+	if err := h.postsUC.DeletePost(c.UserContext(), id); err != nil {
 		if errors.Is(err, apperr.ErrNotFound) {
 			return fiber.NewError(http.StatusNotFound)
 		}
-		slog.Error("delete post", slog.Any("error", err))
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "delete post", slog.Any("error", err))
 		return fiber.NewError(http.StatusInternalServerError)
 	}
 
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/deadline"
+	"github.com/mtvy/blog-api-gateway/migrations"
+	"github.com/pkg/errors"
+)
+
+type migrator interface {
+	Up(ctx context.Context) error
+	Down(ctx context.Context) error
+	Status(ctx context.Context) ([]migrations.Status, error)
+}
+
+type MigrationHandle struct {
+	migrator migrator
+}
+
+func NewMigration(migrator migrator) *MigrationHandle {
+	return &MigrationHandle{
+		migrator: migrator,
+	}
+}
+
+// ListMigrations возвращает миграции и признак их применения.
+//
+//	@Summary		Состояние миграций
+//	@Description	Получить список миграций и признак их применения
+//	@Tags			admin
+//	@Success		200	{object}	map[string][]migrations.Status
+//	@Router			/admin/migrations [get]
+func (h *MigrationHandle) ListMigrations(c *fiber.Ctx) error {
+	return h.sendStatus(c)
+}
+
+// MigrateUp применяет непримененные миграции.
+//
+//	@Summary		Применить миграции
+//	@Description	Применить все непримененные миграции по порядку
+//	@Tags			admin
+//	@Success		200	{object}	map[string][]migrations.Status
+//	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Failure		504	{object}	map[string]interface{}	"Превышено время обработки запроса"
+//	@Router			/admin/migrations/up [post]
+func (h *MigrationHandle) MigrateUp(c *fiber.Ctx) error {
+	if err := h.migrator.Up(c.UserContext()); err != nil {
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "migrate up", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return h.sendStatus(c)
+}
+
+// MigrateDown откатывает последнюю примененную миграцию.
+//
+//	@Summary		Откатить миграцию
+//	@Description	Откатить последнюю примененную миграцию
+//	@Tags			admin
+//	@Success		200	{object}	map[string][]migrations.Status
+//	@Failure		409	{object}	map[string]interface{}	"Нет примененных миграций"
+//	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Failure		504	{object}	map[string]interface{}	"Превышено время обработки запроса"
+//	@Router			/admin/migrations/down [post]
+func (h *MigrationHandle) MigrateDown(c *fiber.Ctx) error {
+	if err := h.migrator.Down(c.UserContext()); err != nil {
+		if errors.Is(err, migrations.ErrNoApplied) {
+			return fiber.NewError(http.StatusConflict, err.Error())
+		}
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "migrate down", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return h.sendStatus(c)
+}
+
+func (h *MigrationHandle) sendStatus(c *fiber.Ctx) error {
+	statuses, err := h.migrator.Status(c.UserContext())
+	if err != nil {
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "migration status", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(fiber.Map{"migrations": statuses})
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/buildinfo"
+	"github.com/mtvy/blog-api-gateway/internal/logger"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/internal/validator"
+)
+
+type configSource interface {
+	Redacted() map[string]any
+}
+
+type cachePurger interface {
+	Purge() int
+}
+
+type maintenanceSwitch interface {
+	Enabled() bool
+	Set(enabled bool)
+}
+
+// OpsHandle обслуживает служебный API на порту admin.port. Эти маршруты не
+// входят в swagger публичного API.
+type OpsHandle struct {
+	config      configSource
+	cache       cachePurger
+	maintenance maintenanceSwitch
+}
+
+func NewOps(config configSource, cache cachePurger, maintenance maintenanceSwitch) *OpsHandle {
+	return &OpsHandle{
+		config:      config,
+		cache:       cache,
+		maintenance: maintenance,
+	}
+}
+
+// GetLogLevel возвращает минимальный уровень журнала.
+func (h *OpsHandle) GetLogLevel(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"level": logger.Level().String()})
+}
+
+// SetLogLevel меняет минимальный уровень журнала до перезапуска или
+// изменения log.level в конфигурации.
+func (h *OpsHandle) SetLogLevel(c *fiber.Ctx) error {
+	req := &models.LogLevelRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := validator.Validate(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := logger.SetLevel(req.Level); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	slog.InfoContext(c.UserContext(), "min log level set: "+logger.Level().String())
+
+	return c.JSON(fiber.Map{"level": logger.Level().String()})
+}
+
+// GetBuildInfo возвращает версию, коммит и версию Go.
+func (h *OpsHandle) GetBuildInfo(c *fiber.Ctx) error {
+	return c.JSON(buildinfo.Get())
+}
+
+// GetConfig возвращает действующую конфигурацию со скрытыми секретами.
+func (h *OpsHandle) GetConfig(c *fiber.Ctx) error {
+	return c.JSON(h.config.Redacted())
+}
+
+// PurgeCache удаляет запомненные ответы идемпотентных запросов.
+func (h *OpsHandle) PurgeCache(c *fiber.Ctx) error {
+	n := h.cache.Purge()
+	slog.InfoContext(c.UserContext(), "idempotency cache purged", slog.Int("entries", n))
+
+	return c.JSON(fiber.Map{"purged": n})
+}
+
+// GetMaintenance возвращает состояние режима обслуживания.
+func (h *OpsHandle) GetMaintenance(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"enabled": h.maintenance.Enabled()})
+}
+
+// SetMaintenance включает или выключает режим обслуживания.
+func (h *OpsHandle) SetMaintenance(c *fiber.Ctx) error {
+	req := &models.MaintenanceRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := validator.Validate(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	h.maintenance.Set(*req.Enabled)
+	slog.WarnContext(c.UserContext(), "maintenance mode changed", slog.Bool("enabled", *req.Enabled))
+
+	return c.JSON(fiber.Map{"enabled": h.maintenance.Enabled()})
+}
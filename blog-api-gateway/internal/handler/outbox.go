@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/deadline"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/pkg/errors"
+)
+
+type outboxProvider interface {
+	ListOutbox(ctx context.Context, status models.OutboxStatus) ([]models.OutboxEntryDTO, error)
+	RetryOutbox(ctx context.Context, id uint64) error
+}
+
+type OutboxHandle struct {
+	outboxUC outboxProvider
+}
+
+func NewOutbox(outboxUC outboxProvider) *OutboxHandle {
+	return &OutboxHandle{
+		outboxUC: outboxUC,
+	}
+}
+
+// ListOutbox возвращает недоставленные события из outbox.
+//
+//	@Summary		Записи outbox
+//	@Description	Получить ожидающие доставки и ошибочные записи outbox
+//	@Tags			admin
+//	@Param			status	query		string	false	"Статус записи"	Enums(pending, failed)
+//	@Success		200		{object}	map[string][]models.OutboxEntryDTO
+//	@Failure		400		{object}	map[string]interface{}	"Некорректный статус"
+//	@Failure		500		{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Router			/admin/outbox [get]
+func (h *OutboxHandle) ListOutbox(c *fiber.Ctx) error {
+	status := models.OutboxStatus(c.Query("status"))
+	switch status {
+	case "", models.OutboxPending, models.OutboxFailed:
+	default:
+		return fiber.NewError(http.StatusBadRequest, "status should be pending or failed")
+	}
+
+	entries, err := h.outboxUC.ListOutbox(c.UserContext(), status)
+	if err != nil {
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "list outbox", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(fiber.Map{"entries": entries})
+}
+
+// RetryOutbox возвращает запись outbox в очередь на доставку.
+//
+//	@Summary		Повторить доставку
+//	@Description	Вернуть запись outbox в очередь на доставку
+//	@Tags			admin
+//	@Param			id	path	int	true	"ID записи"
+//	@Success		200	"Запись поставлена в очередь"
+//	@Failure		400	{object}	map[string]interface{}	"Некорректный ID"
+//	@Failure		404	{object}	map[string]interface{}	"Запись не найдена"
+//	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Router			/admin/outbox/{id}/retry [post]
+func (h *OutboxHandle) RetryOutbox(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "id should be uint")
+	}
+
+	if err := h.outboxUC.RetryOutbox(c.UserContext(), id); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return fiber.NewError(http.StatusNotFound)
+		}
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "retry outbox", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return c.SendStatus(http.StatusOK)
+}
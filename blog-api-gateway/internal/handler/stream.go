@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/stream"
+	"github.com/pkg/errors"
+	"github.com/valyala/fasthttp"
+)
+
+const wsWriteTimeout = 10 * time.Second
+
+type postsStream interface {
+	Subscribe(filter stream.Filter, lastSeq uint64) (*stream.Subscription, []stream.Message)
+}
+
+type StreamHandle struct {
+	hub       postsStream
+	heartbeat time.Duration
+}
+
+func NewStream(hub postsStream, heartbeat time.Duration) *StreamHandle {
+	return &StreamHandle{
+		hub:       hub,
+		heartbeat: heartbeat,
+	}
+}
+
+type streamMessage struct {
+	ID    uint64 `json:"id"`
+	Event any    `json:"event"`
+}
+
+// PostsSSE отдает изменения постов в формате Server-Sent Events.
+//
+//	@Summary		Поток изменений постов (SSE)
+//	@Description	Подписка на создание, изменение и удаление постов. Поддерживает возобновление по заголовку Last-Event-ID
+//	@Tags			posts
+//	@Produce		text/event-stream
+//	@Param			author			query		string	false	"Фильтр по автору (через запятую)"
+//	@Param			post_id			query		string	false	"Фильтр по ID поста (через запятую)"
+//	@Param			Last-Event-ID	header		int		false	"ID последнего полученного события"
+//	@Success		200				{string}	string	"Поток событий"
+//	@Failure		400				{object}	map[string]interface{}	"Некорректный фильтр"
+//	@Router			/posts/stream [get]
+func (h *StreamHandle) PostsSSE(c *fiber.Ctx) error {
+	filter, err := parseFilter(c)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	lastID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	lastSeq, err := parseLastEventID(lastID)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	sub, missed := h.hub.Subscribe(filter, lastSeq)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		ticker := time.NewTicker(h.heartbeat)
+		defer ticker.Stop()
+
+		fmt.Fprintf(w, "retry: %d\n\n", h.heartbeat.Milliseconds())
+		for _, msg := range missed {
+			if err := writeSSE(w, msg); err != nil {
+				return
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case msg, ok := <-sub.C():
+				if !ok {
+					if sub.Lagged() {
+						fmt.Fprint(w, "event: lagged\ndata: {}\n\n")
+						_ = w.Flush()
+					}
+					return
+				}
+				if err := writeSSE(w, msg); err != nil {
+					return
+				}
+			case <-ticker.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}))
+	return nil
+}
+
+// PostsWS отдает изменения постов через WebSocket. Клиент может заменить
+// фильтр, отправив JSON вида {"authors": [...], "post_ids": [...]}.
+//
+//	@Summary		Поток изменений постов (WebSocket)
+//	@Description	Подписка на изменения постов с фильтрами по автору и ID поста
+//	@Tags			posts
+//	@Param			author			query	string	false	"Фильтр по автору (через запятую)"
+//	@Param			post_id			query	string	false	"Фильтр по ID поста (через запятую)"
+//	@Param			last_event_id	query	int		false	"ID последнего полученного события"
+//	@Success		101	"Switching Protocols"
+//	@Failure		400	{object}	map[string]interface{}	"Некорректный фильтр"
+//	@Failure		404	{object}	map[string]interface{}	"WebSocket выключен флагом posts_ws"
+//	@Failure		426	{object}	map[string]interface{}	"Требуется WebSocket"
+//	@Router			/posts/ws [get]
+func (h *StreamHandle) PostsWS(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	filter, err := parseFilter(c)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+	lastSeq, err := parseLastEventID(c.Query("last_event_id"))
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	return websocket.New(func(conn *websocket.Conn) {
+		h.serveWS(conn, filter, lastSeq)
+	})(c)
+}
+
+func (h *StreamHandle) serveWS(conn *websocket.Conn, filter stream.Filter, lastSeq uint64) {
+	sub, missed := h.hub.Subscribe(filter, lastSeq)
+	defer sub.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			var f stream.Filter
+			if err := conn.ReadJSON(&f); err != nil {
+				var syntaxErr *json.SyntaxError
+				var typeErr *json.UnmarshalTypeError
+				if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+					continue
+				}
+				return
+			}
+			sub.SetFilter(f)
+		}
+	}()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for _, msg := range missed {
+		if err := writeWS(conn, msg); err != nil {
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-closed:
+			return
+		case msg, ok := <-sub.C():
+			if !ok {
+				reason := "unsubscribed"
+				if sub.Lagged() {
+					reason = "lagged"
+				}
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+					time.Now().Add(wsWriteTimeout))
+				return
+			}
+			if err := writeWS(conn, msg); err != nil {
+				slog.Debug("ws write", slog.Any("error", err))
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeSSE(w *bufio.Writer, msg stream.Message) error {
+	data, err := json.Marshal(msg.Event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.Seq, msg.Event.Type, data)
+	return err
+}
+
+func writeWS(conn *websocket.Conn, msg stream.Message) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return err
+	}
+	return conn.WriteJSON(streamMessage{ID: msg.Seq, Event: msg.Event})
+}
+
+func parseFilter(c *fiber.Ctx) (stream.Filter, error) {
+	filter := stream.Filter{Authors: queryList(c, "author")}
+	for _, raw := range queryList(c, "post_id") {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return filter, errors.New("post_id should be uint")
+		}
+		filter.PostIDs = append(filter.PostIDs, id)
+	}
+	return filter, nil
+}
+
+func parseLastEventID(raw string) (uint64, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	seq, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, errors.New("last event id should be uint")
+	}
+	return seq, nil
+}
+
+func queryList(c *fiber.Ctx, key string) []string {
+	var values []string
+	for _, raw := range c.Context().QueryArgs().PeekMulti(key) {
+		for _, v := range strings.Split(string(raw), ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
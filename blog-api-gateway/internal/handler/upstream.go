@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/gateway"
+)
+
+type upstreamsProvider interface {
+	Status() []gateway.RouteStatus
+}
+
+type UpstreamHandle struct {
+	gateway upstreamsProvider
+}
+
+func NewUpstream(gateway upstreamsProvider) *UpstreamHandle {
+	return &UpstreamHandle{
+		gateway: gateway,
+	}
+}
+
+// ListUpstreams возвращает состояние экземпляров вышестоящих сервисов.
+//
+//	@Summary		Состояние upstream
+//	@Description	Получить состояние здоровья и нагрузку экземпляров для каждого проксируемого маршрута
+//	@Tags			admin
+//	@Success		200	{object}	map[string][]gateway.RouteStatus
+//	@Router			/admin/upstreams [get]
+func (h *UpstreamHandle) ListUpstreams(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"routes": h.gateway.Status()})
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/deadline"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/internal/validator"
+	"github.com/pkg/errors"
+)
+
+type webhooksProvider interface {
+	ListWebhooks(ctx context.Context) ([]models.WebhookDTO, error)
+	GetWebhook(ctx context.Context, id uint64) (*models.WebhookDTO, error)
+	CreateWebhook(ctx context.Context, webhook models.WebhookDTO) (*models.WebhookDTO, error)
+	UpdateWebhook(ctx context.Context, webhook models.WebhookDTO) error
+	DeleteWebhook(ctx context.Context, id uint64) error
+	ListDeliveries(ctx context.Context, webhookID uint64) ([]models.DeliveryDTO, error)
+	ListDeadLetters(ctx context.Context) ([]models.DeadLetterDTO, error)
+}
+
+type WebhookHandle struct {
+	webhooksUC webhooksProvider
+}
+
+func NewWebhook(webhooksUC webhooksProvider) *WebhookHandle {
+	return &WebhookHandle{
+		webhooksUC: webhooksUC,
+	}
+}
+
+// ListWebhooks возвращает список вебхуков.
+//
+//	@Summary		Список вебхуков
+//	@Description	Получить список зарегистрированных вебхуков
+//	@Tags			webhooks
+//	@Success		200	{object}	map[string][]models.WebhookDTO
+//	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Failure		504	{object}	map[string]interface{}	"Превышено время обработки запроса"
+//	@Router			/webhooks [get]
+func (h *WebhookHandle) ListWebhooks(c *fiber.Ctx) error {
+	webhooks, err := h.webhooksUC.ListWebhooks(c.UserContext())
+	if err != nil {
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "list webhooks", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return c.JSON(fiber.Map{"webhooks": webhooks})
+}
+
+// GetWebhook получает вебхук по ID.
+//
+//	@Summary		Получить вебхук
+//	@Description	Получить вебхук по идентификатору
+//	@Tags			webhooks
+//	@Param			id	path		int	true	"ID вебхука"
+//	@Success		200	{object}	models.WebhookDTO
+//	@Failure		400	{object}	map[string]interface{}	"Некорректный ID"
+//	@Failure		404	{object}	map[string]interface{}	"Вебхук не найден"
+//	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Failure		504	{object}	map[string]interface{}	"Превышено время обработки запроса"
+//	@Router			/webhooks/{id} [get]
+func (h *WebhookHandle) GetWebhook(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "id should be uint")
+	}
+
+	webhook, err := h.webhooksUC.GetWebhook(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return fiber.NewError(http.StatusNotFound)
+		}
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "get webhook", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	webhook.Secret = ""
+	return c.JSON(webhook)
+}
+
+// CreateWebhook регистрирует новый вебхук. Секрет подписи возвращается только в ответе на создание.
+//
+//	@Summary		Создать вебхук
+//	@Description	Зарегистрировать вебхук на события постов
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			webhook	body		models.CreateWebhookRequest	true	"Данные вебхука"
+//	@Success		200		{object}	models.WebhookDTO
+//	@Failure		400		{object}	map[string]interface{}	"Ошибка валидации"
+//	@Failure		500		{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Failure		504		{object}	map[string]interface{}	"Превышено время обработки запроса"
+//	@Router			/webhooks [post]
+func (h *WebhookHandle) CreateWebhook(c *fiber.Ctx) error {
+	req := &models.CreateWebhookRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.Validate(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	webhook, err := h.webhooksUC.CreateWebhook(c.UserContext(), req.ToDTO())
+	if err != nil {
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "create webhook", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(webhook)
+}
+
+// UpdateWebhook обновляет вебхук. Пустой секрет оставляет прежний.
+//
+//	@Summary		Обновить вебхук
+//	@Description	Обновить существующий вебхук
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			webhook	body		models.UpdateWebhookRequest	true	"Данные для обновления"
+//	@Success		200		{object}	map[string]uint64			"ID обновленного вебхука"
+//	@Failure		400		{object}	map[string]interface{}		"Ошибка валидации"
+//	@Failure		404		{object}	map[string]interface{}		"Вебхук не найден"
+//	@Failure		500		{object}	map[string]interface{}		"Внутренняя ошибка сервера"
+//	@Failure		504		{object}	map[string]interface{}		"Превышено время обработки запроса"
+//	@Router			/webhooks [put]
+func (h *WebhookHandle) UpdateWebhook(c *fiber.Ctx) error {
+	req := &models.UpdateWebhookRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := validator.Validate(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	webhook := req.ToDTO()
+	if err := h.webhooksUC.UpdateWebhook(c.UserContext(), webhook); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return fiber.NewError(http.StatusNotFound)
+		}
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "update webhook", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(fiber.Map{"id": webhook.ID})
+}
+
+// DeleteWebhook удаляет вебхук по ID.
+//
+//	@Summary		Удалить вебхук
+//	@Description	Удалить вебхук по идентификатору
+//	@Tags			webhooks
+//	@Param			id	path	int	true	"ID вебхука"
+//	@Success		200	"Вебхук удален"
+//	@Failure		400	{object}	map[string]interface{}	"Некорректный ID"
+//	@Failure		404	{object}	map[string]interface{}	"Вебхук не найден"
+//	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Failure		504	{object}	map[string]interface{}	"Превышено время обработки запроса"
+//	@Router			/webhooks/{id} [delete]
+func (h *WebhookHandle) DeleteWebhook(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "id should be uint")
+	}
+
+	if err := h.webhooksUC.DeleteWebhook(c.UserContext(), id); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return fiber.NewError(http.StatusNotFound)
+		}
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "delete webhook", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return c.SendStatus(http.StatusOK)
+}
+
+// ListDeliveries возвращает журнал доставок вебхука.
+//
+//	@Summary		Журнал доставок
+//	@Description	Получить последние попытки доставки событий на вебхук
+//	@Tags			webhooks
+//	@Param			id	path		int	true	"ID вебхука"
+//	@Success		200	{object}	map[string][]models.DeliveryDTO
+//	@Failure		400	{object}	map[string]interface{}	"Некорректный ID"
+//	@Failure		404	{object}	map[string]interface{}	"Вебхук не найден"
+//	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Failure		504	{object}	map[string]interface{}	"Превышено время обработки запроса"
+//	@Router			/webhooks/{id}/deliveries [get]
+func (h *WebhookHandle) ListDeliveries(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "id should be uint")
+	}
+
+	deliveries, err := h.webhooksUC.ListDeliveries(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return fiber.NewError(http.StatusNotFound)
+		}
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "list deliveries", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(fiber.Map{"deliveries": deliveries})
+}
+
+// ListDeadLetters возвращает события, которые не удалось доставить.
+//
+//	@Summary		Недоставленные события
+//	@Description	Получить события, доставка которых исчерпала все попытки
+//	@Tags			webhooks
+//	@Success		200	{object}	map[string][]models.DeadLetterDTO
+//	@Failure		500	{object}	map[string]interface{}	"Внутренняя ошибка сервера"
+//	@Failure		504	{object}	map[string]interface{}	"Превышено время обработки запроса"
+//	@Router			/webhooks/dead-letters [get]
+func (h *WebhookHandle) ListDeadLetters(c *fiber.Ctx) error {
+	letters, err := h.webhooksUC.ListDeadLetters(c.UserContext())
+	if err != nil {
+		if deadline.IsContextError(err) {
+			return err
+		}
+		slog.ErrorContext(c.UserContext(), "list dead letters", slog.Any("error", err))
+		return fiber.NewError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(fiber.Map{"dead_letters": letters})
+}
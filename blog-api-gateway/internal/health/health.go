@@ -0,0 +1,230 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+	// StatusDegraded - готовность не нарушена, но необязательная
+	// зависимость недоступна.
+	StatusDegraded = "degraded"
+)
+
+var ErrDraining = errors.New("shutting down")
+
+// Config: Timeout - срок проверки, для которой при регистрации не задан
+// собственный.
+type Config struct {
+	Timeout time.Duration `mapstructure:"timeout" validate:"gt=0"`
+}
+
+// CheckFunc проверяет доступность зависимости. Проверка должна завершаться
+// при отмене ctx.
+type CheckFunc func(ctx context.Context) error
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Error  string                 `json:"error,omitempty"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+	// Dependencies - проверки необязательных зависимостей.
+	Dependencies map[string]CheckResult `json:"dependencies,omitempty"`
+	// State - состояние сервиса, не влияющее на готовность, например
+	// режим обслуживания.
+	State map[string]any `json:"state,omitempty"`
+}
+
+type check struct {
+	name     string
+	timeout  time.Duration
+	fn       CheckFunc
+	optional bool
+}
+
+// Health отвечает на пробы живости и готовности. Сервис готов, пока
+// остановка не начата и все обязательные проверки успешны; отказ
+// необязательных только отмечается в отчете.
+type Health struct {
+	cfg Config
+
+	mu     sync.RWMutex
+	checks []check
+	state  map[string]func() any
+
+	draining atomic.Bool
+}
+
+func New(cfg Config) *Health {
+	return &Health{
+		cfg: cfg,
+	}
+}
+
+// Register добавляет проверку готовности. При timeout <= 0 используется
+// Config.Timeout.
+func (h *Health) Register(name string, timeout time.Duration, fn CheckFunc) {
+	h.register(check{name: name, timeout: timeout, fn: fn})
+}
+
+// RegisterOptional добавляет проверку зависимости, без которой сервис
+// продолжает работать: ее отказ показывается в отчете, но не снимает
+// готовность.
+func (h *Health) RegisterOptional(name string, timeout time.Duration, fn CheckFunc) {
+	h.register(check{name: name, timeout: timeout, fn: fn, optional: true})
+}
+
+func (h *Health) register(c check) {
+	if c.timeout <= 0 {
+		c.timeout = h.cfg.Timeout
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, c)
+}
+
+// Describe добавляет в отчет о готовности состояние name, которое вычисляется
+// при каждом запросе.
+func (h *Health) Describe(name string, fn func() any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.state == nil {
+		h.state = make(map[string]func() any)
+	}
+	h.state[name] = fn
+}
+
+// Drain переводит готовность в отказ перед остановкой, чтобы балансировщик
+// перестал направлять новые запросы.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Check выполняет все проверки параллельно, каждую со своим сроком, и
+// добавляет состояние сервиса. Отказ необязательной проверки дает статус
+// StatusDegraded.
+func (h *Health) Check(ctx context.Context) Report {
+	if h.draining.Load() {
+		return Report{Status: StatusFail, Error: ErrDraining.Error(), State: h.describe()}
+	}
+
+	h.mu.RLock()
+	checks := append([]check(nil), h.checks...)
+	h.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		if !c.optional {
+			report.Checks[c.name] = results[i]
+			if results[i].Status != StatusOK {
+				report.Status = StatusFail
+			}
+			continue
+		}
+		if report.Dependencies == nil {
+			report.Dependencies = make(map[string]CheckResult)
+		}
+		report.Dependencies[c.name] = results[i]
+		if results[i].Status != StatusOK && report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	report.State = h.describe()
+	return report
+}
+
+func (h *Health) describe() map[string]any {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.state) == 0 {
+		return nil
+	}
+	state := make(map[string]any, len(h.state))
+	for name, fn := range h.state {
+		state[name] = fn()
+	}
+	return state
+}
+
+func run(ctx context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = errors.Wrap(ctx.Err(), "check timed out")
+	}
+
+	result := CheckResult{Status: StatusOK, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Liveness отвечает 200, пока процесс способен обрабатывать запросы.
+//
+//	@Summary		Проверка живости
+//	@Description	Процесс запущен и отвечает на запросы
+//	@Tags			health
+//	@Success		200	{object}	Report
+//	@Router			/healthz [get]
+func (h *Health) Liveness(c *fiber.Ctx) error {
+	return c.JSON(Report{Status: StatusOK})
+}
+
+// Readiness отвечает 200, если сервис готов принимать запросы, иначе 503.
+// Отказ необязательной зависимости и состояние сервиса видны в отчете, но
+// готовность не снимают.
+//
+//	@Summary		Проверка готовности
+//	@Description	Результаты проверок зависимостей, состояние шлюза и режим обслуживания; 503, пока идет запуск или остановка либо не прошла обязательная проверка. Отказ необязательной зависимости дает статус degraded и код 200
+//	@Tags			health
+//	@Success		200	{object}	Report
+//	@Failure		503	{object}	Report
+//	@Router			/readyz [get]
+func (h *Health) Readiness(c *fiber.Ctx) error {
+	report := h.Check(c.UserContext())
+	if report.Status == StatusFail {
+		return c.Status(http.StatusServiceUnavailable).JSON(report)
+	}
+	return c.JSON(report)
+}
@@ -0,0 +1,180 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readiness(t *testing.T, h *Health) (int, Report) {
+	app := fiber.New()
+	app.Get("/healthz", h.Liveness)
+	app.Get("/readyz", h.Readiness)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "liveness does not depend on checks")
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil), -1)
+	require.NoError(t, err)
+	var report Report
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	return resp.StatusCode, report
+}
+
+func TestHealth_Readiness(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failed := func(context.Context) error { return errors.New("connection refused") }
+	hung := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	testCases := []struct {
+		name       string
+		checks     map[string]CheckFunc
+		drain      bool
+		wantCode   int
+		wantStatus map[string]string
+	}{
+		{
+			name:       "ready",
+			checks:     map[string]CheckFunc{"repository": ok, "nats": ok},
+			wantCode:   http.StatusOK,
+			wantStatus: map[string]string{"repository": StatusOK, "nats": StatusOK},
+		},
+		{
+			name:       "check_failed",
+			checks:     map[string]CheckFunc{"repository": ok, "nats": failed},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: map[string]string{"repository": StatusOK, "nats": StatusFail},
+		},
+		{
+			name:       "check_timed_out",
+			checks:     map[string]CheckFunc{"gateway": hung},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: map[string]string{"gateway": StatusFail},
+		},
+		{
+			name:     "draining",
+			checks:   map[string]CheckFunc{"repository": ok},
+			drain:    true,
+			wantCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := New(Config{Timeout: 20 * time.Millisecond})
+			for name, fn := range tc.checks {
+				h.Register(name, 0, fn)
+			}
+			if tc.drain {
+				h.Drain()
+			}
+
+			code, report := readiness(t, h)
+			assert.Equal(t, tc.wantCode, code)
+			for name, status := range tc.wantStatus {
+				assert.Equal(t, status, report.Checks[name].Status, name)
+			}
+			if tc.drain {
+				assert.Equal(t, ErrDraining.Error(), report.Error)
+			}
+		})
+	}
+}
+
+func TestHealth_CheckTimeout(t *testing.T) {
+	h := New(Config{Timeout: time.Second})
+	h.Register("slow", 10*time.Millisecond, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report := h.Check(context.Background())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Contains(t, report.Checks["slow"].Error, "check timed out")
+}
+
+func TestHealth_Optional(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failed := func(context.Context) error { return errors.New("connection refused") }
+
+	testCases := []struct {
+		name       string
+		required   CheckFunc
+		optional   CheckFunc
+		wantCode   int
+		wantStatus string
+	}{
+		{
+			name:       "ok",
+			required:   ok,
+			optional:   ok,
+			wantCode:   http.StatusOK,
+			wantStatus: StatusOK,
+		},
+		{
+			name:       "optional_failed",
+			required:   ok,
+			optional:   failed,
+			wantCode:   http.StatusOK,
+			wantStatus: StatusDegraded,
+		},
+		{
+			name:       "required_failed",
+			required:   failed,
+			optional:   failed,
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: StatusFail,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := New(Config{Timeout: 20 * time.Millisecond})
+			h.Register("repository", 0, tc.required)
+			h.RegisterOptional("nats", 0, tc.optional)
+
+			code, report := readiness(t, h)
+			assert.Equal(t, tc.wantCode, code)
+			assert.Equal(t, tc.wantStatus, report.Status)
+			assert.Contains(t, report.Checks, "repository")
+			assert.NotContains(t, report.Checks, "nats")
+			assert.Contains(t, report.Dependencies, "nats")
+		})
+	}
+}
+
+func TestHealth_State(t *testing.T) {
+	h := New(Config{Timeout: time.Second})
+	h.Register("repository", 0, func(context.Context) error { return nil })
+
+	code, report := readiness(t, h)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, report.State)
+
+	enabled := true
+	h.Describe("maintenance", func() any { return enabled })
+	code, report = readiness(t, h)
+	assert.Equal(t, http.StatusOK, code, "state does not affect readiness")
+	assert.Equal(t, map[string]any{"maintenance": true}, report.State)
+
+	enabled = false
+	h.Drain()
+	code, report = readiness(t, h)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, map[string]any{"maintenance": false}, report.State)
+}
@@ -0,0 +1,43 @@
+// Package httpx содержит общие части middleware, которым нужен итог запроса:
+// шаблон маршрута и окончательный код ответа, а также признак внутреннего
+// запроса.
+package httpx
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type internalKey struct{}
+
+// WithInternal помечает запрос, который шлюз выполняет сам для себя (части
+// составных маршрутов).
+func WithInternal(ctx context.Context) context.Context {
+	return context.WithValue(ctx, internalKey{}, true)
+}
+
+// Internal сообщает, выполняется ли запрос шлюзом для себя.
+func Internal(ctx context.Context) bool {
+	internal, _ := ctx.Value(internalKey{}).(bool)
+	return internal
+}
+
+// Next вызывает следующие обработчики и возвращает шаблон маршрута,
+// обработавшего запрос, или "", если подходящего маршрута нет. Ошибка
+// обработчика сразу передается обработчику ошибок приложения, так что после
+// Next код ответа окончательный; сама ошибка возвращается для span и логов,
+// а не для повторной обработки.
+func Next(c *fiber.Ctx) (route string, err error) {
+	self := c.Route()
+	err = c.Next()
+	if c.Route() != self {
+		route = c.Route().Path
+	}
+	if err != nil {
+		if err := c.App().ErrorHandler(c, err); err != nil {
+			_ = c.SendStatus(fiber.StatusInternalServerError)
+		}
+	}
+	return route, err
+}
@@ -0,0 +1,65 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNext(t *testing.T) {
+	type want struct {
+		route  string
+		status int
+		err    bool
+	}
+
+	testCases := []struct {
+		name string
+		url  string
+		want want
+	}{
+		{
+			name: "matched",
+			url:  "/posts/1",
+			want: want{route: "/posts/:id", status: http.StatusOK},
+		},
+		{
+			name: "handler_error",
+			url:  "/posts/0",
+			want: want{route: "/posts/:id", status: http.StatusNotFound, err: true},
+		},
+		{
+			name: "unmatched",
+			url:  "/unknown",
+			want: want{status: http.StatusNotFound, err: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got want
+			app := fiber.New()
+			app.Use(func(c *fiber.Ctx) error {
+				route, err := Next(c)
+				got = want{route: route, status: c.Response().StatusCode(), err: err != nil}
+				return nil
+			})
+			app.Get("/posts/:id", func(c *fiber.Ctx) error {
+				if c.Params("id") == "0" {
+					return fiber.NewError(http.StatusNotFound)
+				}
+				return c.SendString("post")
+			})
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, tc.url, nil))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.want.status, resp.StatusCode, "error is already handled")
+		})
+	}
+}
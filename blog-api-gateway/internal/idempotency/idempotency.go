@@ -0,0 +1,151 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLen  = 255
+	sweepEvery = 256
+)
+
+type Config struct {
+	TTL time.Duration `mapstructure:"ttl" validate:"gt=0"`
+}
+
+type entry struct {
+	fingerprint string
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+}
+
+// Cache запоминает ответы на запросы с заголовком Idempotency-Key и
+// повторяет их для повторных запросов с тем же ключом. Ключи разделены по
+// клиенту, методу и пути.
+type Cache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	ops     int
+}
+
+func New(cfg Config) *Cache {
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &Cache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Handle выполняет запрос один раз на ключ. Пока первый запрос выполняется,
+// дубликаты получают 409, запрос с тем же ключом и другим телом - 422.
+// Ошибки не запоминаются, чтобы клиент мог повторить запрос.
+func (ic *Cache) Handle(c *fiber.Ctx) error {
+	key := c.Get(HeaderKey)
+	if key == "" {
+		return c.Next()
+	}
+	if len(key) > maxKeyLen {
+		return fiber.NewError(http.StatusBadRequest, "idempotency key is too long")
+	}
+
+	scope := scopeKey(c, key)
+	fingerprint := fingerprint(c)
+
+	ic.mu.Lock()
+	now := ic.now()
+	ic.ops++
+	if ic.ops%sweepEvery == 0 {
+		ic.sweep(now)
+	}
+	e, ok := ic.entries[scope]
+	if ok && now.After(e.expiresAt) {
+		ok = false
+	}
+	if ok {
+		prev := *e
+		ic.mu.Unlock()
+		switch {
+		case prev.fingerprint != fingerprint:
+			return fiber.NewError(http.StatusUnprocessableEntity, "idempotency key is reused with a different request")
+		case !prev.done:
+			return fiber.NewError(http.StatusConflict, "request with this idempotency key is in progress")
+		}
+		c.Set(HeaderReplayed, "true")
+		c.Set(fiber.HeaderContentType, prev.contentType)
+		return c.Status(prev.status).Send(prev.body)
+	}
+	e = &entry{fingerprint: fingerprint, expiresAt: now.Add(ic.ttl)}
+	ic.entries[scope] = e
+	ic.mu.Unlock()
+
+	err := c.Next()
+	status := c.Response().StatusCode()
+
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	if err != nil || status >= http.StatusInternalServerError {
+		if ic.entries[scope] == e {
+			delete(ic.entries, scope)
+		}
+		return err
+	}
+	e.done = true
+	e.status = status
+	e.contentType = string(c.Response().Header.ContentType())
+	e.body = append([]byte(nil), c.Response().Body()...)
+	return nil
+}
+
+// Purge удаляет все запомненные ответы и возвращает их число. Запросы,
+// выполняющиеся во время очистки, не запоминаются.
+func (ic *Cache) Purge() int {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	n := len(ic.entries)
+	ic.entries = make(map[string]*entry)
+	return n
+}
+
+func (ic *Cache) sweep(now time.Time) {
+	for key, e := range ic.entries {
+		if e.done && now.After(e.expiresAt) {
+			delete(ic.entries, key)
+		}
+	}
+}
+
+// scopeKey отделяет ключи разных клиентов. Без аутентификации клиент
+// определяется по IP.
+func scopeKey(c *fiber.Ctx, key string) string {
+	client := "ip:" + c.IP()
+	if id, ok := auth.FromContext(c); ok {
+		client = "sub:" + id.Subject
+	}
+	return client + " " + c.Method() + " " + c.Path() + " " + key
+}
+
+func fingerprint(c *fiber.Ctx) string {
+	sum := sha256.Sum256(c.Body())
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,167 @@
+package idempotency
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testApp struct {
+	app     *fiber.App
+	cache   *Cache
+	created atomic.Int32
+	release chan struct{}
+}
+
+func newTestApp(t *testing.T) *testApp {
+	ta := &testApp{cache: New(Config{TTL: time.Hour})}
+	ta.app = fiber.New()
+	ta.app.Post("/posts", ta.cache.Handle, func(c *fiber.Ctx) error {
+		if ta.release != nil {
+			<-ta.release
+		}
+		if strings.Contains(string(c.Body()), "fail") {
+			return fiber.NewError(http.StatusInternalServerError)
+		}
+		id := ta.created.Add(1)
+		return c.Status(http.StatusCreated).JSON(fiber.Map{"id": id})
+	})
+	return ta
+}
+
+func (ta *testApp) post(t *testing.T, key, body string) (*http.Response, string) {
+	req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	resp, err := ta.app.Test(req, -1)
+	require.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(b)
+}
+
+func TestCache_Replay(t *testing.T) {
+	ta := newTestApp(t)
+
+	resp, body := ta.post(t, "k1", `{"title":"a"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, `{"id":1}`, body)
+
+	resp, body = ta.post(t, "k1", `{"title":"a"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, `{"id":1}`, body)
+	assert.Equal(t, "true", resp.Header.Get(HeaderReplayed))
+	assert.Equal(t, fiber.MIMEApplicationJSON, resp.Header.Get(fiber.HeaderContentType))
+
+	resp, _ = ta.post(t, "k1", `{"title":"b"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	_, body = ta.post(t, "k2", `{"title":"a"}`)
+	assert.Equal(t, `{"id":2}`, body)
+
+	_, body = ta.post(t, "", `{"title":"a"}`)
+	assert.Equal(t, `{"id":3}`, body)
+	assert.Equal(t, int32(3), ta.created.Load())
+}
+
+func TestCache_AnonymousClients(t *testing.T) {
+	cache := New(Config{TTL: time.Hour})
+	var created atomic.Int32
+	app := fiber.New(fiber.Config{ProxyHeader: fiber.HeaderXForwardedFor})
+	app.Post("/posts", cache.Handle, func(c *fiber.Ctx) error {
+		return c.Status(http.StatusCreated).JSON(fiber.Map{"id": created.Add(1)})
+	})
+
+	post := func(ip string) string {
+		req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{"title":"a"}`))
+		req.Header.Set(HeaderKey, "k1")
+		req.Header.Set(fiber.HeaderXForwardedFor, ip)
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	assert.Equal(t, `{"id":1}`, post("10.0.0.1"))
+	assert.Equal(t, `{"id":2}`, post("10.0.0.2"), "anonymous clients do not share keys")
+	assert.Equal(t, `{"id":1}`, post("10.0.0.1"))
+}
+
+func TestCache_ErrorIsNotStored(t *testing.T) {
+	ta := newTestApp(t)
+
+	resp, _ := ta.post(t, "k1", `fail`)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	resp, _ = ta.post(t, "k1", `fail`)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(HeaderReplayed))
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	ta := newTestApp(t)
+	ta.release = make(chan struct{})
+
+	first := make(chan int)
+	go func() {
+		resp, _ := ta.post(t, "k1", `{}`)
+		first <- resp.StatusCode
+	}()
+
+	require.Eventually(t, func() bool {
+		ta.cache.mu.Lock()
+		defer ta.cache.mu.Unlock()
+		return len(ta.cache.entries) == 1
+	}, time.Second, time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, _ := ta.post(t, "k1", `{}`)
+			assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		}()
+	}
+	wg.Wait()
+
+	close(ta.release)
+	assert.Equal(t, http.StatusCreated, <-first)
+	assert.Equal(t, int32(1), ta.created.Load())
+}
+
+func TestCache_TTL(t *testing.T) {
+	ta := newTestApp(t)
+	now := time.Now()
+	ta.cache.now = func() time.Time { return now }
+
+	ta.post(t, "k1", `{}`)
+	now = now.Add(2 * time.Hour)
+	_, body := ta.post(t, "k1", `{}`)
+	assert.Equal(t, `{"id":2}`, body)
+}
+
+func TestCache_Purge(t *testing.T) {
+	ta := newTestApp(t)
+
+	ta.post(t, "k1", `{"title":"a"}`)
+	ta.post(t, "k2", `{"title":"b"}`)
+	assert.Equal(t, 2, ta.cache.Purge())
+	assert.Zero(t, ta.cache.Purge())
+
+	resp, body := ta.post(t, "k1", `{"title":"a"}`)
+	assert.Empty(t, resp.Header.Get(HeaderReplayed), "purged response is not replayed")
+	assert.Equal(t, `{"id":3}`, body)
+}
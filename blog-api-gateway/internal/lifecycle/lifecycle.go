@@ -0,0 +1,118 @@
+package lifecycle
+
+import (
+	"context"
+	stderrors "errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Config: DrainPeriod - время между отказом готовности и закрытием
+// слушателя, за которое балансировщик перестает направлять запросы.
+// Timeout ограничивает остановку целиком: ожидание текущих запросов и
+// остановку компонентов; половина срока поровну зарезервирована за
+// компонентами, так что каждый получает хотя бы свою долю.
+type Config struct {
+	DrainPeriod time.Duration `mapstructure:"drain_period" validate:"gte=0"`
+	Timeout     time.Duration `mapstructure:"timeout" validate:"gt=0"`
+}
+
+// StopFunc останавливает компонент. Остановка должна завершаться при
+// отмене ctx.
+type StopFunc func(ctx context.Context) error
+
+type hook struct {
+	name string
+	stop StopFunc
+}
+
+// Manager останавливает зарегистрированные компоненты в порядке, обратном
+// регистрации: компонент, запущенный позже, может зависеть от запущенных
+// раньше, поэтому останавливается первым.
+type Manager struct {
+	mu    sync.Mutex
+	hooks []hook
+}
+
+func New() *Manager {
+	return &Manager{}
+}
+
+func (m *Manager) Register(name string, stop StopFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks = append(m.hooks, hook{name: name, stop: stop})
+}
+
+// RegisterFunc регистрирует остановку, не принимающую контекст. Если она не
+// завершится до отмены ctx, Shutdown перейдет к следующему компоненту.
+func (m *Manager) RegisterFunc(name string, stop func()) {
+	m.Register(name, func(context.Context) error {
+		stop()
+		return nil
+	})
+}
+
+// RegisterCloser регистрирует остановку вида Close() error.
+func (m *Manager) RegisterCloser(name string, closer func() error) {
+	m.Register(name, func(context.Context) error {
+		return closer()
+	})
+}
+
+// Shutdown останавливает компоненты по одному и возвращает все ошибки.
+// Если у ctx есть срок, компонент должен остановиться раньше него на
+// резерв для оставшихся: половина срока поровну делится между компонентами,
+// поэтому зависший компонент не лишает времени следующие (например, сброс
+// трассировки, которая останавливается последней). Повторный вызов ничего не
+// делает.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	hooks := m.hooks
+	m.hooks = nil
+	m.mu.Unlock()
+
+	deadline, hasDeadline := ctx.Deadline()
+	var reserve time.Duration
+	if hasDeadline && len(hooks) > 0 {
+		reserve = time.Until(deadline) / time.Duration(2*len(hooks))
+	}
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		start := time.Now()
+		// после h останавливаются еще i компонентов
+		hookCtx, cancel := context.WithCancel(ctx)
+		if hasDeadline {
+			hookCtx, cancel = context.WithDeadline(ctx, deadline.Add(-time.Duration(i)*reserve))
+		}
+		err := stop(hookCtx, h.stop)
+		cancel()
+		if err != nil {
+			slog.Error("stop "+h.name, slog.Any("error", err))
+			errs = append(errs, errors.Wrapf(err, "stop %s", h.name))
+			continue
+		}
+		slog.Debug("stopped "+h.name, slog.Duration("took", time.Since(start)))
+	}
+	return stderrors.Join(errs...)
+}
+
+func stop(ctx context.Context, fn StopFunc) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
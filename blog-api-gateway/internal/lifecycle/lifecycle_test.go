@@ -0,0 +1,70 @@
+package lifecycle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_Shutdown(t *testing.T) {
+	var stopped []string
+	m := New()
+	m.RegisterFunc("dispatcher", func() { stopped = append(stopped, "dispatcher") })
+	m.RegisterCloser("publisher", func() error {
+		stopped = append(stopped, "publisher")
+		return errors.New("connection reset")
+	})
+	m.Register("server", func(context.Context) error {
+		stopped = append(stopped, "server")
+		return nil
+	})
+
+	err := m.Shutdown(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stop publisher: connection reset")
+	assert.Equal(t, []string{"server", "publisher", "dispatcher"}, stopped)
+
+	require.NoError(t, m.Shutdown(context.Background()), "second shutdown is a no-op")
+	assert.Len(t, stopped, 3)
+}
+
+func TestManager_ShutdownTimeout(t *testing.T) {
+	m := New()
+	m.RegisterFunc("relay", func() { time.Sleep(time.Second) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := m.Shutdown(ctx)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "stop relay")
+}
+
+func TestManager_ShutdownBudget(t *testing.T) {
+	var tracingErr error
+	m := New()
+	m.Register("tracing", func(ctx context.Context) error {
+		tracingErr = ctx.Err()
+		return nil
+	})
+	m.Register("server", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := m.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "stop server")
+	assert.NotContains(t, err.Error(), "stop tracing")
+	assert.NoError(t, tracingErr, "a stuck component leaves time for the next ones")
+}
@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+	"github.com/mtvy/blog-api-gateway/internal/httpx"
+)
+
+const (
+	HeaderRequestID = "X-Request-ID"
+
+	// maxRequestIDLen ограничивает длину принятого от клиента идентификатора.
+	maxRequestIDLen = 128
+)
+
+// AccessConfig: SampleRate - доля успешных запросов, попадающих в журнал,
+// ответы с ошибкой журналируются всегда. ExcludePaths - пути, запросы к
+// которым не журналируются (вместе с вложенными путями).
+type AccessConfig struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	SampleRate   float64  `mapstructure:"sample_rate" validate:"gte=0,lte=1"`
+	ExcludePaths []string `mapstructure:"exclude_paths" validate:"dive,startswith=/"`
+}
+
+type requestIDKey struct{}
+
+// WithRequestID сохраняет идентификатор запроса в ctx. Записи журнала,
+// сделанные с этим контекстом, содержат request_id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Access принимает X-Request-ID клиента или создает новый, возвращает его в
+// ответе и пишет по строке журнала на запрос. Ошибки пишутся всегда,
+// успешные запросы - с долей cfg.SampleRate.
+func Access(cfg AccessConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		id := c.Get(HeaderRequestID)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Set(HeaderRequestID, id)
+		c.SetUserContext(WithRequestID(c.UserContext(), id))
+
+		route, _ := httpx.Next(c)
+
+		status := c.Response().StatusCode()
+		if !cfg.Enabled || excluded(cfg.ExcludePaths, c.Path()) {
+			return nil
+		}
+		if status < http.StatusBadRequest && rand.Float64() >= cfg.SampleRate {
+			return nil
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("ip", c.IP()),
+		}
+		// тело потокового ответа не читается, чтобы не дожидаться его конца
+		if !c.Response().IsBodyStream() {
+			attrs = append(attrs, slog.Int("bytes", len(c.Response().Body())))
+		}
+		if identity, ok := auth.FromContext(c); ok {
+			attrs = append(attrs, slog.String("user", identity.Subject))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		slog.LogAttrs(c.UserContext(), level, "access", attrs...)
+		return nil
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func excluded(paths []string, path string) bool {
+	for _, p := range paths {
+		if path == p || strings.HasPrefix(path, strings.TrimSuffix(p, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(contextHandler{slog.NewJSONHandler(&buf, nil)}))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		out = append(out, entry)
+	}
+	return out
+}
+
+func newTestApp(cfg AccessConfig) *fiber.App {
+	app := fiber.New()
+	app.Use(Access(cfg))
+	app.Get("/posts/:id", func(c *fiber.Ctx) error {
+		if c.Params("id") == "0" {
+			slog.ErrorContext(c.UserContext(), "get post")
+			return fiber.NewError(http.StatusInternalServerError)
+		}
+		return c.SendString("post")
+	})
+	app.Get("/metrics", func(c *fiber.Ctx) error {
+		return c.SendString("metrics")
+	})
+	return app
+}
+
+func TestAccess_RequestID(t *testing.T) {
+	captureLogs(t)
+	app := newTestApp(AccessConfig{})
+
+	testCases := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "accepted", header: "req-42", keep: true},
+		{name: "generated", header: ""},
+		{name: "invalid", header: "bad id", keep: false},
+		{name: "too_long", header: strings.Repeat("a", maxRequestIDLen+1), keep: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+			if tc.header != "" {
+				req.Header.Set(HeaderRequestID, tc.header)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+
+			id := resp.Header.Get(HeaderRequestID)
+			assert.NotEmpty(t, id)
+			if tc.keep {
+				assert.Equal(t, tc.header, id)
+			} else {
+				assert.NotEqual(t, tc.header, id)
+			}
+		})
+	}
+}
+
+func TestAccess_Log(t *testing.T) {
+	buf := captureLogs(t)
+	app := newTestApp(AccessConfig{Enabled: true, SampleRate: 1, ExcludePaths: []string{"/metrics"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	_, err := app.Test(req)
+	require.NoError(t, err)
+	_, err = app.Test(httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.NoError(t, err)
+
+	logs := entries(t, buf)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "access", logs[0]["msg"])
+	assert.Equal(t, "INFO", logs[0]["level"])
+	assert.Equal(t, "req-1", logs[0]["request_id"])
+	assert.Equal(t, "GET", logs[0]["method"])
+	assert.Equal(t, "/posts/:id", logs[0]["route"])
+	assert.EqualValues(t, http.StatusOK, logs[0]["status"])
+	assert.EqualValues(t, len("post"), logs[0]["bytes"])
+	assert.Contains(t, logs[0], "latency")
+	assert.Contains(t, logs[0], "ip")
+}
+
+func TestAccess_Sampling(t *testing.T) {
+	buf := captureLogs(t)
+	app := newTestApp(AccessConfig{Enabled: true, SampleRate: 0})
+
+	for _, url := range []string{"/posts/1", "/posts/0"} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set(HeaderRequestID, "req-2")
+		_, err := app.Test(req)
+		require.NoError(t, err)
+	}
+
+	logs := entries(t, buf)
+	require.Len(t, logs, 2, "successful request is sampled out, failed one is kept")
+	assert.Equal(t, "get post", logs[0]["msg"])
+	assert.Equal(t, "req-2", logs[0]["request_id"], "request-scoped records carry the request id")
+	assert.Equal(t, "access", logs[1]["msg"])
+	assert.Equal(t, "ERROR", logs[1]["level"])
+	assert.EqualValues(t, http.StatusInternalServerError, logs[1]["status"])
+}
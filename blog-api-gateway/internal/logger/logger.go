@@ -1,27 +1,76 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 const DefaultLevel = slog.LevelInfo
 
 type Config struct {
-	Level string `validate:"required,log_level"`
+	Level  string `validate:"required,log_level"`
+	Access AccessConfig
 }
 
+// level - минимальный уровень журнала, его можно менять без пересоздания
+// обработчика.
+var level slog.LevelVar
+
 func Register(cfg Config) {
-	var lvl slog.Level
-	if err := lvl.UnmarshalText([]byte(cfg.Level)); err != nil {
-		lvl = DefaultLevel
+	if err := SetLevel(cfg.Level); err != nil {
+		level.Set(DefaultLevel)
 		slog.Warn(fmt.Sprintf("invalid min log level in cfg. Using default: %s",
 			DefaultLevel.String()), slog.Any("error", err))
 	} else {
-		slog.Info("min log level set: " + lvl.String())
+		slog.Info("min log level set: " + level.Level().String())
 	}
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: lvl}))
+	logger := slog.New(contextHandler{slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: &level})})
 
 	slog.SetDefault(logger)
 }
+
+// SetLevel меняет минимальный уровень журнала. При ошибке уровень не
+// меняется.
+func SetLevel(name string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(name)); err != nil {
+		return err
+	}
+	level.Set(lvl)
+	return nil
+}
+
+func Level() slog.Level {
+	return level.Level()
+}
+
+// contextHandler добавляет в записи с контекстом идентификаторы запроса,
+// трассы и span.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,60 @@
+package maintenance
+
+import (
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/pkg/errors"
+)
+
+// Config: Enabled включает режим обслуживания при запуске и перезагрузке
+// конфигурации, RetryAfter - через сколько клиенту повторить запрос.
+type Config struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	RetryAfter time.Duration `mapstructure:"retry_after" validate:"gt=0"`
+}
+
+// Mode - режим обслуживания: запросы, меняющие данные, отклоняются, чтение
+// продолжает работать. Переключается во время работы.
+type Mode struct {
+	enabled    atomic.Bool
+	retryAfter atomic.Int64
+}
+
+func New(cfg Config) *Mode {
+	m := &Mode{}
+	m.Set(cfg.Enabled)
+	m.SetRetryAfter(cfg.RetryAfter)
+	return m
+}
+
+func (m *Mode) Enabled() bool {
+	return m.enabled.Load()
+}
+
+func (m *Mode) Set(enabled bool) {
+	m.enabled.Store(enabled)
+}
+
+func (m *Mode) SetRetryAfter(d time.Duration) {
+	m.retryAfter.Store(int64(d))
+}
+
+// Handle отклоняет запросы, кроме GET, HEAD и OPTIONS, пока включен режим
+// обслуживания: ответ 503 с Retry-After.
+func (m *Mode) Handle(c *fiber.Ctx) error {
+	if !m.Enabled() {
+		return c.Next()
+	}
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return c.Next()
+	}
+	retryAfter := time.Duration(m.retryAfter.Load())
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	return errors.Wrap(apperr.ErrMaintenance, "writes are disabled")
+}
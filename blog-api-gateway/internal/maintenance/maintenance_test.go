@@ -0,0 +1,55 @@
+package maintenance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMode_Handle(t *testing.T) {
+	mode := New(Config{RetryAfter: 90 * time.Second})
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			if errors.Is(err, apperr.ErrMaintenance) {
+				return c.SendStatus(http.StatusServiceUnavailable)
+			}
+			return fiber.DefaultErrorHandler(c, err)
+		},
+	})
+	app.Use("/posts", mode.Handle)
+	app.All("/posts", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+
+	do := func(method string) *http.Response {
+		resp, err := app.Test(httptest.NewRequest(method, "/posts", nil))
+		require.NoError(t, err)
+		return resp
+	}
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPost).StatusCode)
+
+	mode.Set(true)
+	assert.True(t, mode.Enabled())
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		resp := do(method)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, method)
+		assert.Equal(t, "90", resp.Header.Get(fiber.HeaderRetryAfter), method)
+	}
+	assert.Equal(t, http.StatusOK, do(http.MethodGet).StatusCode)
+	assert.Equal(t, http.StatusOK, do(http.MethodHead).StatusCode)
+
+	mode.SetRetryAfter(1500 * time.Millisecond)
+	assert.Equal(t, "2", do(http.MethodPost).Header.Get(fiber.HeaderRetryAfter), "rounded up to seconds")
+
+	mode.Set(false)
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete).StatusCode)
+}
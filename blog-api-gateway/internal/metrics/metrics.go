@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/mtvy/blog-api-gateway/internal/httpx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "blog"
+
+	unmatchedRoute = "unmatched"
+)
+
+// Config: при нулевом Port метрики отдаются на основном порту API.
+type Config struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Port    int    `mapstructure:"port" validate:"port"`
+	Path    string `mapstructure:"path" validate:"omitempty,startswith=/"`
+}
+
+// Metrics собирает RED-метрики HTTP-запросов по шаблону маршрута и
+// доменные показатели.
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+	breakers *prometheus.GaugeVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route template and status class.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route template and status class.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests being served by method.",
+		}, []string{"method"}),
+		breakers: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "gateway_circuit_breaker_state",
+			Help:      "1 for the current circuit breaker state of a gateway route.",
+		}, []string{"route", "state"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.duration,
+		m.inFlight,
+		m.breakers,
+	)
+	return m
+}
+
+// Gauge регистрирует показатель, значение которого вычисляется при каждом
+// сборе метрик.
+func (m *Metrics) Gauge(name, help string, value func() float64) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, value))
+}
+
+// LabeledGauge регистрирует показатель с одной меткой label, значения
+// которого вычисляются при каждом сборе метрик.
+func (m *Metrics) LabeledGauge(name, help, label string, values func() map[string]float64) {
+	m.registry.MustRegister(&labeledGauge{
+		desc:   prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, []string{label}, nil),
+		values: values,
+	})
+}
+
+// SetBreakerState отмечает state текущим состоянием автомата размыкания
+// маршрута шлюза route. Пустой state удаляет показатель маршрута.
+func (m *Metrics) SetBreakerState(route, state string) {
+	m.breakers.DeletePartialMatch(prometheus.Labels{"route": route})
+	if state != "" {
+		m.breakers.WithLabelValues(route, state).Set(1)
+	}
+}
+
+// Handle учитывает запрос в счетчике и гистограмме по методу, шаблону
+// маршрута и классу кода ответа. Пока запрос обрабатывается, шаблон еще
+// неизвестен, поэтому запросы в обработке считаются только по методу.
+func (m *Metrics) Handle(c *fiber.Ctx) error {
+	start := time.Now()
+	method := c.Method()
+
+	inFlight := m.inFlight.WithLabelValues(method)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	route, _ := httpx.Next(c)
+	if route == "" {
+		route = unmatchedRoute
+	}
+
+	status := strconv.Itoa(c.Response().StatusCode()/100) + "xx"
+	m.requests.WithLabelValues(method, route, status).Inc()
+	m.duration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// Handler отдает метрики в формате Prometheus.
+func (m *Metrics) Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+type labeledGauge struct {
+	desc   *prometheus.Desc
+	values func() map[string]float64
+}
+
+func (g *labeledGauge) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+func (g *labeledGauge) Collect(ch chan<- prometheus.Metric) {
+	for label, value := range g.values() {
+		ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, value, label)
+	}
+}
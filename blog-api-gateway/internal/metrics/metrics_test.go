@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	m.Gauge("posts", "Number of stored posts.", func() float64 { return 42 })
+	m.LabeledGauge("outbox_entries", "Outbox entries.", "status", func() map[string]float64 {
+		return map[string]float64{"pending": 3}
+	})
+	m.SetBreakerState("/users", "closed")
+	m.SetBreakerState("/users", "open")
+	m.SetBreakerState("/orders", "closed")
+	m.SetBreakerState("/orders", "")
+
+	app := fiber.New()
+	app.Use(m.Handle)
+	app.Get("/metrics", m.Handler())
+	app.Get("/posts/:id", func(c *fiber.Ctx) error {
+		if c.Params("id") == "0" {
+			return fiber.NewError(http.StatusNotFound)
+		}
+		return c.SendString("post")
+	})
+
+	for _, url := range []string{"/posts/1", "/posts/2", "/posts/0", "/unknown"} {
+		_, err := app.Test(httptest.NewRequest(http.MethodGet, url, nil))
+		require.NoError(t, err)
+	}
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	for _, want := range []string{
+		`blog_http_requests_total{method="GET",route="/posts/:id",status="2xx"} 2`,
+		`blog_http_requests_total{method="GET",route="/posts/:id",status="4xx"} 1`,
+		`blog_http_requests_total{method="GET",route="unmatched",status="4xx"} 1`,
+		`blog_http_request_duration_seconds_count{method="GET",route="/posts/:id",status="2xx"} 2`,
+		`blog_http_requests_in_flight{method="GET"} 1`,
+		`blog_posts 42`,
+		`blog_outbox_entries{status="pending"} 3`,
+		`blog_gateway_circuit_breaker_state{route="/users",state="open"} 1`,
+		`go_goroutines`,
+	} {
+		assert.Contains(t, string(body), want)
+	}
+	assert.NotContains(t, string(body), `state="closed"`, "only the current state is reported")
+	assert.NotContains(t, string(body), `route="/orders"`, "removed routes are dropped")
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+const (
+	ScopePostsRead  = "posts:read"
+	ScopePostsWrite = "posts:write"
+	ScopeAdmin      = "admin"
+)
+
+// APIKeyDTO описывает ключ API. Сам ключ не хранится, только его хеш.
+type APIKeyDTO struct {
+	ID         uint64     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	Hash       string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Valid возвращает true, если ключ не отозван и не истек.
+func (k APIKeyDTO) Valid(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=255"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=posts:read posts:write admin"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (c CreateAPIKeyRequest) ToDTO() APIKeyDTO {
+	return APIKeyDTO{
+		Name:      c.Name,
+		Scopes:    c.Scopes,
+		ExpiresAt: c.ExpiresAt,
+	}
+}
+
+// IssuedAPIKeyDTO возвращается при выпуске и ротации ключа: только в этом
+// ответе ключ передается открытым текстом.
+type IssuedAPIKeyDTO struct {
+	APIKeyDTO
+	Key string `json:"key"`
+}
@@ -0,0 +1,74 @@
+package models
+
+// DatasetDTO - данные блога в формате начального наполнения, экспорта и
+// импорта.
+type DatasetDTO struct {
+	Posts []PostRecordDTO `json:"posts"`
+}
+
+type PostRecordDTO struct {
+	ID      uint64 `json:"id"`
+	Title   string `json:"title" validate:"required,max=255"`
+	Author  string `json:"author" validate:"required,max=255"`
+	Content string `json:"content"`
+}
+
+func NewPostRecord(post PostDTO) PostRecordDTO {
+	return PostRecordDTO{
+		ID:      post.ID,
+		Title:   post.Title,
+		Author:  post.Author,
+		Content: post.Content,
+	}
+}
+
+func (r PostRecordDTO) ToDTO() PostDTO {
+	return PostDTO{
+		ID:      r.ID,
+		Title:   r.Title,
+		Author:  r.Author,
+		Content: r.Content,
+	}
+}
+
+// ImportMode определяет, что делать с постом, идентификатор которого уже
+// занят.
+type ImportMode string
+
+const (
+	// ImportFail отклоняет импорт целиком, ничего не сохраняя.
+	ImportFail ImportMode = "fail"
+	// ImportSkip оставляет существующий пост.
+	ImportSkip ImportMode = "skip"
+	// ImportOverwrite заменяет существующий пост.
+	ImportOverwrite ImportMode = "overwrite"
+	// ImportRemap сохраняет все посты с новыми идентификаторами.
+	ImportRemap ImportMode = "remap"
+)
+
+type ImportReportDTO struct {
+	Processed int `json:"processed"`
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Skipped   int `json:"skipped"`
+	// Remapped - новые идентификаторы постов по исходным в режиме remap.
+	Remapped map[uint64]uint64 `json:"remapped,omitempty"`
+}
+
+type ImportStatus string
+
+const (
+	ImportRunning ImportStatus = "running"
+	ImportDone    ImportStatus = "done"
+	ImportFailed  ImportStatus = "failed"
+)
+
+// ImportProgressDTO - строка хода импорта в ответе application/x-ndjson:
+// running после каждого сохраненного пакета, затем done с итогом или failed
+// с кодом ответа, который получил бы обычный запрос, и описанием ошибки.
+type ImportProgressDTO struct {
+	Status ImportStatus `json:"status"`
+	ImportReportDTO
+	Code  int    `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PostEventType string
+
+const (
+	PostCreated PostEventType = "post.created"
+	PostUpdated PostEventType = "post.updated"
+	PostDeleted PostEventType = "post.deleted"
+)
+
+type PostEvent struct {
+	ID         string        `json:"id"`
+	Type       PostEventType `json:"type"`
+	PostID     uint64        `json:"post_id"`
+	Post       PostDTO       `json:"post"`
+	OccurredAt time.Time     `json:"occurred_at"`
+}
+
+func NewPostEvent(eventType PostEventType, post PostDTO) PostEvent {
+	return PostEvent{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		PostID:     post.ID,
+		Post:       post,
+		OccurredAt: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// MigrationDTO - примененная миграция. PostIDs - посты, созданные ее Up;
+// откат удаляет только их.
+type MigrationDTO struct {
+	Version   int
+	PostIDs   []uint64
+	AppliedAt time.Time
+}
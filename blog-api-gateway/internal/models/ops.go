@@ -0,0 +1,9 @@
+package models
+
+type LogLevelRequest struct {
+	Level string `json:"level" validate:"required"`
+}
+
+type MaintenanceRequest struct {
+	Enabled *bool `json:"enabled" validate:"required"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "pending"
+	OutboxFailed  OutboxStatus = "failed"
+)
+
+type OutboxEntryDTO struct {
+	ID        uint64       `json:"id"`
+	Event     PostEvent    `json:"event"`
+	Status    OutboxStatus `json:"status"`
+	Attempts  int          `json:"attempts"`
+	LastError string       `json:"last_error,omitempty"`
+	// Delivered - приемники, уже получившие событие; повтор их пропускает.
+	Delivered     []string  `json:"delivered,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+}
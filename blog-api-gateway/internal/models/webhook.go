@@ -0,0 +1,82 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+type WebhookDTO struct {
+	ID        uint64          `json:"id"`
+	URL       string          `json:"url"`
+	Events    []PostEventType `json:"events"`
+	Secret    string          `json:"secret,omitempty"`
+	Active    bool            `json:"active"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Subscribed возвращает true, если вебхук активен и подписан на событие.
+// Пустой список событий означает подписку на все события.
+func (w WebhookDTO) Subscribed(eventType PostEventType) bool {
+	if !w.Active {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	return slices.Contains(w.Events, eventType)
+}
+
+type CreateWebhookRequest struct {
+	URL    string          `json:"url" validate:"required,url,max=2048"`
+	Events []PostEventType `json:"events" validate:"dive,oneof=post.created post.updated post.deleted"`
+	Secret string          `json:"secret" validate:"omitempty,min=16,max=256"`
+}
+
+func (c CreateWebhookRequest) ToDTO() WebhookDTO {
+	return WebhookDTO{
+		URL:    c.URL,
+		Events: c.Events,
+		Secret: c.Secret,
+		Active: true,
+	}
+}
+
+type UpdateWebhookRequest struct {
+	ID     uint64          `json:"id" validate:"required"`
+	URL    string          `json:"url" validate:"required,url,max=2048"`
+	Events []PostEventType `json:"events" validate:"dive,oneof=post.created post.updated post.deleted"`
+	Secret string          `json:"secret" validate:"omitempty,min=16,max=256"`
+	Active bool            `json:"active"`
+}
+
+func (c UpdateWebhookRequest) ToDTO() WebhookDTO {
+	return WebhookDTO{
+		ID:     c.ID,
+		URL:    c.URL,
+		Events: c.Events,
+		Secret: c.Secret,
+		Active: c.Active,
+	}
+}
+
+type DeliveryDTO struct {
+	ID         uint64        `json:"id"`
+	WebhookID  uint64        `json:"webhook_id"`
+	EventID    string        `json:"event_id"`
+	EventType  PostEventType `json:"event_type"`
+	Attempt    int           `json:"attempt"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Success    bool          `json:"success"`
+	DurationMs int64         `json:"duration_ms"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type DeadLetterDTO struct {
+	ID        uint64    `json:"id"`
+	WebhookID uint64    `json:"webhook_id"`
+	Event     PostEvent `json:"event"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,185 @@
+package outbox
+
+import (
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/pkg/errors"
+)
+
+type Config struct {
+	Sinks          []string      `mapstructure:"sinks" validate:"dive,oneof=log stream webhook nats"`
+	PollInterval   time.Duration `mapstructure:"poll_interval" validate:"gt=0"`
+	BatchSize      int           `mapstructure:"batch_size" validate:"gt=0"`
+	MaxAttempts    int           `mapstructure:"max_attempts" validate:"gt=0"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff" validate:"gte=0"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff" validate:"gtefield=InitialBackoff"`
+}
+
+type Sink interface {
+	Send(event models.PostEvent) error
+}
+
+type SinkFunc func(event models.PostEvent) error
+
+func (f SinkFunc) Send(event models.PostEvent) error {
+	return f(event)
+}
+
+// LogSink пишет события в лог.
+var LogSink = SinkFunc(func(event models.PostEvent) error {
+	slog.Info("post event",
+		slog.String("event_id", event.ID),
+		slog.String("type", string(event.Type)),
+		slog.Uint64("post_id", event.PostID))
+	return nil
+})
+
+type store interface {
+	OutboxSignal() <-chan struct{}
+	PendingOutbox(limit int, now time.Time) ([]models.OutboxEntryDTO, error)
+	CompleteOutbox(id uint64) error
+	FailOutbox(id uint64, delivered []string, reason string, retryAt time.Time, failed bool) error
+}
+
+// Relay переносит события из outbox в приемники с семантикой at-least-once:
+// запись удаляется только после успешной отправки во все приемники. Повтор
+// отправляет событие только в приемники, где отправка не удалась; приемник
+// получит событие повторно, только если сбой произошел после его отправки.
+type Relay struct {
+	cfg      Config
+	store    store
+	sinks    map[string]Sink
+	done     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func NewRelay(cfg Config, store store, sinks map[string]Sink) *Relay {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	return &Relay{
+		cfg:   cfg,
+		store: store,
+		sinks: sinks,
+		done:  make(chan struct{}),
+	}
+}
+
+func (r *Relay) Start() {
+	r.wg.Add(1)
+	go r.run()
+}
+
+// Stop дожидается завершения текущей итерации и отправляет накопившиеся
+// события.
+func (r *Relay) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.done)
+	})
+	r.wg.Wait()
+}
+
+func (r *Relay) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.drain(r.done)
+
+		select {
+		case <-r.done:
+			// события, записанные после последней итерации
+			r.drain(nil)
+			return
+		case <-ticker.C:
+		case <-r.store.OutboxSignal():
+		}
+	}
+}
+
+// drain отправляет ожидающие события, пока их не останется или не закроется
+// interrupt. Нулевой interrupt не прерывает отправку.
+func (r *Relay) drain(interrupt <-chan struct{}) {
+	for {
+		entries, err := r.store.PendingOutbox(r.cfg.BatchSize, time.Now().UTC())
+		if err != nil {
+			slog.Error("read outbox", slog.Any("error", err))
+			return
+		}
+		if len(entries) == 0 {
+			return
+		}
+
+		for _, e := range entries {
+			select {
+			case <-interrupt:
+				return
+			default:
+			}
+			r.process(e)
+		}
+	}
+}
+
+func (r *Relay) process(e models.OutboxEntryDTO) {
+	delivered, err := r.send(e)
+	if err != nil {
+		attempt := e.Attempts + 1
+		failed := attempt >= r.cfg.MaxAttempts
+		if failed {
+			slog.Error("outbox entry failed",
+				slog.Uint64("outbox_id", e.ID),
+				slog.String("event_id", e.Event.ID),
+				slog.Any("error", err))
+		}
+		retryAt := time.Now().UTC().Add(r.backoff(attempt))
+		if err := r.store.FailOutbox(e.ID, delivered, err.Error(), retryAt, failed); err != nil {
+			slog.Error("fail outbox entry", slog.Any("error", err))
+		}
+		return
+	}
+
+	if err := r.store.CompleteOutbox(e.ID); err != nil {
+		slog.Error("complete outbox entry", slog.Any("error", err))
+	}
+}
+
+// send отправляет событие записи e в приемники, которые его еще не
+// получили, и возвращает все приемники, получившие событие.
+func (r *Relay) send(e models.OutboxEntryDTO) ([]string, error) {
+	delivered := slices.Clone(e.Delivered)
+	var failed []string
+	for name, sink := range r.sinks {
+		if slices.Contains(e.Delivered, name) {
+			continue
+		}
+		if err := sink.Send(e.Event); err != nil {
+			failed = append(failed, name+": "+err.Error())
+			continue
+		}
+		delivered = append(delivered, name)
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		sort.Strings(delivered)
+		return delivered, errors.New(strings.Join(failed, "; "))
+	}
+	return delivered, nil
+}
+
+func (r *Relay) backoff(attempt int) time.Duration {
+	delay := r.cfg.InitialBackoff << (attempt - 1)
+	if delay <= 0 || (r.cfg.MaxBackoff > 0 && delay > r.cfg.MaxBackoff) {
+		delay = r.cfg.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,149 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/internal/repository"
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordSink struct {
+	mu     sync.Mutex
+	events []models.PostEvent
+	fails  int
+}
+
+func (s *recordSink) Send(event models.PostEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fails > 0 {
+		s.fails--
+		return errors.New("sink unavailable")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *recordSink) received() []models.PostEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]models.PostEvent(nil), s.events...)
+}
+
+func startRelay(t *testing.T, repo *repository.PostRepo, sinks map[string]Sink) {
+	relay := NewRelay(Config{
+		PollInterval:   time.Millisecond,
+		BatchSize:      10,
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}, repo, sinks)
+	relay.Start()
+	t.Cleanup(relay.Stop)
+}
+
+func outboxEmpty(repo *repository.PostRepo, status models.OutboxStatus) func() bool {
+	return func() bool {
+		entries, _ := repo.ListOutbox(context.Background(), status)
+		return len(entries) == 0
+	}
+}
+
+func TestRelay_Deliver(t *testing.T) {
+	repo := repository.NewPostProvider()
+	sink := &recordSink{}
+
+	id, err := repo.CreatePost(context.Background(), models.PostDTO{Title: "Title", Author: "Author"})
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdatePost(context.Background(), models.PostDTO{ID: id, Title: "New", Author: "Author"}))
+	require.NoError(t, repo.DeletePost(context.Background(), id))
+
+	startRelay(t, repo, map[string]Sink{"test": sink})
+	require.Eventually(t, outboxEmpty(repo, ""), time.Second, time.Millisecond)
+
+	events := sink.received()
+	require.Len(t, events, 3)
+	assert.Equal(t, models.PostCreated, events[0].Type)
+	assert.Equal(t, models.PostUpdated, events[1].Type)
+	assert.Equal(t, models.PostDeleted, events[2].Type)
+	assert.Equal(t, "New", events[2].Post.Title)
+}
+
+func TestRelay_Retry(t *testing.T) {
+	repo := repository.NewPostProvider()
+	sink := &recordSink{fails: 2}
+
+	_, err := repo.CreatePost(context.Background(), models.PostDTO{Title: "Title"})
+	require.NoError(t, err)
+
+	startRelay(t, repo, map[string]Sink{"test": sink})
+	require.Eventually(t, outboxEmpty(repo, ""), time.Second, time.Millisecond)
+	assert.Len(t, sink.received(), 1)
+}
+
+func TestRelay_RetryFailedSinks(t *testing.T) {
+	repo := repository.NewPostProvider()
+	stable := &recordSink{}
+	flaky := &recordSink{fails: 3}
+
+	_, err := repo.CreatePost(context.Background(), models.PostDTO{Title: "Title"})
+	require.NoError(t, err)
+
+	startRelay(t, repo, map[string]Sink{"stable": stable, "flaky": flaky})
+	require.Eventually(t, outboxEmpty(repo, models.OutboxPending), time.Second, time.Millisecond)
+
+	failed, err := repo.ListOutbox(context.Background(), models.OutboxFailed)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, []string{"stable"}, failed[0].Delivered)
+	assert.Equal(t, "flaky: sink unavailable", failed[0].LastError)
+
+	require.NoError(t, repo.RetryOutbox(context.Background(), failed[0].ID))
+	require.Eventually(t, outboxEmpty(repo, ""), time.Second, time.Millisecond)
+	assert.Len(t, stable.received(), 1, "delivered sinks are not retried")
+	assert.Len(t, flaky.received(), 1)
+}
+
+func TestRelay_Failed(t *testing.T) {
+	repo := repository.NewPostProvider()
+	sink := &recordSink{fails: 3}
+
+	_, err := repo.CreatePost(context.Background(), models.PostDTO{Title: "Title"})
+	require.NoError(t, err)
+
+	startRelay(t, repo, map[string]Sink{"test": sink})
+	require.Eventually(t, outboxEmpty(repo, models.OutboxPending), time.Second, time.Millisecond)
+
+	failed, err := repo.ListOutbox(context.Background(), models.OutboxFailed)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, 3, failed[0].Attempts)
+	assert.Equal(t, "test: sink unavailable", failed[0].LastError)
+
+	require.NoError(t, repo.RetryOutbox(context.Background(), failed[0].ID))
+	require.Eventually(t, outboxEmpty(repo, ""), time.Second, time.Millisecond)
+	assert.Len(t, sink.received(), 1)
+}
+
+func TestRelay_StopFlushes(t *testing.T) {
+	repo := repository.NewPostProvider()
+	sink := &recordSink{}
+
+	relay := NewRelay(Config{PollInterval: time.Hour, BatchSize: 10, MaxAttempts: 1}, repo, map[string]Sink{"test": sink})
+	relay.Start()
+
+	_, err := repo.CreatePost(context.Background(), models.PostDTO{Title: "Title"})
+	require.NoError(t, err)
+	relay.Stop()
+
+	assert.Len(t, sink.received(), 1)
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const sweepEvery = 1024
+
+type bucketState struct {
+	tokens float64
+	ts     time.Time
+	full   time.Time
+}
+
+// MemoryStore хранит корзины в памяти процесса. Заполненные корзины
+// периодически удаляются.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucketState
+	takes   int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		now:     time.Now,
+		buckets: make(map[string]*bucketState),
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, b Bucket) (bool, float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.takes++
+	if s.takes%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	state, ok := s.buckets[key]
+	if !ok {
+		state = &bucketState{tokens: float64(b.Capacity), ts: now}
+		s.buckets[key] = state
+	}
+
+	elapsed := now.Sub(state.ts).Seconds()
+	if elapsed > 0 {
+		state.tokens = math.Min(float64(b.Capacity), state.tokens+elapsed*b.Rate)
+		state.ts = now
+	}
+
+	allowed := state.tokens >= 1
+	if allowed {
+		state.tokens--
+	}
+	state.full = now.Add(time.Duration((float64(b.Capacity) - state.tokens) / b.Rate * float64(time.Second)))
+	return allowed, state.tokens, nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, state := range s.buckets {
+		if !now.Before(state.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
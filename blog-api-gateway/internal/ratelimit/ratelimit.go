@@ -0,0 +1,245 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+	"github.com/mtvy/blog-api-gateway/internal/httpx"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	KeyIP      = "ip"
+	KeySubject = "subject"
+	KeyAPIKey  = "api_key"
+
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+)
+
+type Config struct {
+	Enabled bool        `mapstructure:"enabled"`
+	Store   string      `mapstructure:"store" validate:"omitempty,oneof=memory redis"`
+	Redis   RedisConfig `mapstructure:"redis"`
+	Rules   []Rule      `mapstructure:"rules" validate:"dive"`
+}
+
+// Rule ограничивает запросы к группе маршрутов с префиксом Prefix: каждому
+// клиенту доступно Limit запросов за Period с накоплением до Burst. Пустой
+// Methods означает все методы.
+type Rule struct {
+	Name    string        `mapstructure:"name"`
+	Prefix  string        `mapstructure:"prefix" validate:"required,startswith=/"`
+	Methods []string      `mapstructure:"methods" validate:"dive,alpha"`
+	Key     string        `mapstructure:"key" validate:"omitempty,oneof=ip subject api_key"`
+	Limit   int           `mapstructure:"limit" validate:"gt=0"`
+	Period  time.Duration `mapstructure:"period" validate:"gt=0"`
+	Burst   int           `mapstructure:"burst" validate:"gte=0"`
+}
+
+// Bucket - параметры корзины токенов: емкость и скорость пополнения в
+// токенах в секунду.
+type Bucket struct {
+	Capacity int
+	Rate     float64
+}
+
+// Store списывает токен из корзины key и возвращает, разрешен ли запрос, и
+// остаток токенов после списания.
+type Store interface {
+	Take(ctx context.Context, key string, b Bucket) (allowed bool, tokens float64, err error)
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter - промежуточный обработчик, ограничивающий частоту запросов по
+// правилам конфигурации.
+type Limiter struct {
+	rules atomic.Pointer[ruleSet]
+	store Store
+}
+
+type ruleSet struct {
+	enabled bool
+	rules   []Rule
+}
+
+func New(cfg Config, store Store) (*Limiter, error) {
+	l := &Limiter{store: store}
+	if err := l.Update(cfg); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Update заменяет правила и признак включения. Накопленные корзины
+// сохраняются: ключ корзины зависит только от имени правила и клиента. При
+// ошибке правила не меняются.
+func (l *Limiter) Update(cfg Config) error {
+	rules := make([]Rule, 0, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		if !strings.HasPrefix(rule.Prefix, "/") {
+			return errors.Errorf("rule %s: prefix should start with /", rule.Name)
+		}
+		if rule.Limit <= 0 || rule.Period <= 0 {
+			return errors.Errorf("rule %s: limit and period should be positive", rule.Name)
+		}
+		switch rule.Key {
+		case "":
+			rule.Key = KeyIP
+		case KeyIP, KeySubject, KeyAPIKey:
+		default:
+			return errors.Errorf("rule %s: unknown key %q", rule.Name, rule.Key)
+		}
+		if rule.Burst <= 0 {
+			rule.Burst = rule.Limit
+		}
+		if rule.Name == "" {
+			rule.Name = rule.Prefix
+		}
+		methods := make([]string, len(rule.Methods))
+		for i, method := range rule.Methods {
+			methods[i] = strings.ToUpper(method)
+		}
+		rule.Methods = methods
+		rules = append(rules, rule)
+	}
+
+	l.rules.Store(&ruleSet{enabled: cfg.Enabled, rules: rules})
+	return nil
+}
+
+// NewStore создает хранилище, выбранное в конфигурации.
+func NewStore(cfg Config) (Store, error) {
+	switch cfg.Store {
+	case "", StoreMemory:
+		return NewMemoryStore(), nil
+	case StoreRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		return NewRedisStore(client), nil
+	}
+	return nil, errors.Errorf("unknown rate limit store %q", cfg.Store)
+}
+
+// Handle применяет все подходящие правила. Отказ любого из них завершает
+// запрос ответом 429, заголовки RateLimit-* описывают самое строгое правило.
+// Ошибки хранилища не блокируют запросы. Внутренние запросы шлюза не
+// ограничиваются: их лимит уже учтен исходным запросом.
+func (l *Limiter) Handle(c *fiber.Ctx) error {
+	p := l.rules.Load()
+	if !p.enabled || httpx.Internal(c.UserContext()) {
+		return c.Next()
+	}
+
+	var (
+		strictest *Result
+		policy    Rule
+	)
+	for _, rule := range p.rules {
+		if !rule.match(c) {
+			continue
+		}
+
+		res, err := l.take(c, rule)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "rate limit", slog.String("rule", rule.Name), slog.Any("error", err))
+			continue
+		}
+		if strictest == nil || !res.Allowed || res.Remaining < strictest.Remaining {
+			strictest, policy = &res, rule
+		}
+		if !res.Allowed {
+			break
+		}
+	}
+	if strictest == nil {
+		return c.Next()
+	}
+
+	c.Set("RateLimit-Limit", strconv.Itoa(strictest.Limit))
+	c.Set("RateLimit-Remaining", strconv.Itoa(strictest.Remaining))
+	c.Set("RateLimit-Reset", strconv.Itoa(seconds(strictest.Reset)))
+	c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", policy.Limit, seconds(policy.Period), policy.Burst))
+
+	if !strictest.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds(strictest.RetryAfter)))
+		return fiber.NewError(http.StatusTooManyRequests, "rate limit exceeded")
+	}
+	return c.Next()
+}
+
+func (l *Limiter) take(c *fiber.Ctx, rule Rule) (Result, error) {
+	b := Bucket{
+		Capacity: rule.Burst,
+		Rate:     float64(rule.Limit) / rule.Period.Seconds(),
+	}
+	key := "ratelimit:" + rule.Name + ":" + clientKey(c, rule.Key)
+
+	allowed, tokens, err := l.store.Take(c.UserContext(), key, b)
+	if err != nil {
+		return Result{}, err
+	}
+
+	res := Result{
+		Allowed:   allowed,
+		Limit:     b.Capacity,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(b.Capacity) - tokens) / b.Rate * float64(time.Second)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / b.Rate * float64(time.Second))
+	}
+	return res, nil
+}
+
+func (r Rule) match(c *fiber.Ctx) bool {
+	path := c.Path()
+	if path != r.Prefix && !strings.HasPrefix(path, strings.TrimSuffix(r.Prefix, "/")+"/") {
+		return false
+	}
+	return len(r.Methods) == 0 || slices.Contains(r.Methods, c.Method())
+}
+
+// clientKey возвращает идентификатор клиента для правила. Без субъекта или
+// ключа API клиент определяется по IP. Ключ API хранится только в виде хеша.
+func clientKey(c *fiber.Ctx, key string) string {
+	switch key {
+	case KeySubject:
+		if id, ok := auth.FromContext(c); ok {
+			return "sub:" + id.Subject
+		}
+	case KeyAPIKey:
+		if apiKey := c.Get(auth.HeaderAPIKey); apiKey != "" {
+			sum := sha256.Sum256([]byte(apiKey))
+			return "key:" + hex.EncodeToString(sum[:16])
+		}
+	}
+	return "ip:" + c.IP()
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
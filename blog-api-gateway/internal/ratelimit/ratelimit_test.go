@@ -0,0 +1,220 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/auth"
+	"github.com/mtvy/blog-api-gateway/internal/httpx"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"redis":  NewRedisStore(client),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			b := Bucket{Capacity: 2, Rate: 20}
+			ctx := context.Background()
+
+			allowed, tokens, err := store.Take(ctx, "a", b)
+			require.NoError(t, err)
+			assert.True(t, allowed)
+			assert.InDelta(t, 1, tokens, 0.1)
+
+			allowed, _, err = store.Take(ctx, "a", b)
+			require.NoError(t, err)
+			assert.True(t, allowed)
+
+			allowed, _, err = store.Take(ctx, "a", b)
+			require.NoError(t, err)
+			assert.False(t, allowed, "bucket is empty")
+
+			allowed, _, err = store.Take(ctx, "b", b)
+			require.NoError(t, err)
+			assert.True(t, allowed, "buckets are per key")
+
+			time.Sleep(60 * time.Millisecond)
+			allowed, _, err = store.Take(ctx, "a", b)
+			require.NoError(t, err)
+			assert.True(t, allowed, "bucket is refilled")
+		})
+	}
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	b := Bucket{Capacity: 1, Rate: 1}
+	_, _, _ = s.Take(context.Background(), "a", b)
+	now = now.Add(2 * time.Second)
+	s.sweep(now)
+	assert.Empty(t, s.buckets)
+}
+
+func newTestApp(t *testing.T, rules ...Rule) *fiber.App {
+	l, err := New(Config{Enabled: true, Rules: rules}, NewMemoryStore())
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if sub := c.Get("X-Subject"); sub != "" {
+			auth.WithIdentity(c, &auth.Identity{Subject: sub})
+		}
+		if c.Get("X-Internal") != "" {
+			c.SetUserContext(httpx.WithInternal(c.UserContext()))
+		}
+		return c.Next()
+	})
+	app.Use(l.Handle)
+	app.All("/*", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+	return app
+}
+
+func TestLimiter_Handle(t *testing.T) {
+	app := newTestApp(t, Rule{
+		Name:    "create_post",
+		Prefix:  "/posts",
+		Methods: []string{"post"},
+		Limit:   2,
+		Period:  time.Minute,
+	})
+
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/posts", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("RateLimit-Limit"))
+		assert.Equal(t, "2;w=60;burst=2", resp.Header.Get("RateLimit-Policy"))
+	}
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/posts", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", resp.Header.Get(fiber.HeaderRetryAfter))
+	assert.Equal(t, "60", resp.Header.Get("RateLimit-Reset"))
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/posts/1", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "method is not limited")
+	assert.Empty(t, resp.Header.Get("RateLimit-Limit"))
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodPost, "/postsx", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "path is outside the prefix")
+
+	req := httptest.NewRequest(http.MethodPost, "/posts", nil)
+	req.Header.Set("X-Internal", "1")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "internal requests are not limited")
+}
+
+func TestLimiter_Keys(t *testing.T) {
+	testCases := []struct {
+		name    string
+		key     string
+		headers []map[string]string
+		want    []int
+	}{
+		{
+			name:    "ip",
+			key:     KeyIP,
+			headers: []map[string]string{{"X-API-Key": "a"}, {"X-API-Key": "b"}},
+			want:    []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:    "api_key",
+			key:     KeyAPIKey,
+			headers: []map[string]string{{"X-API-Key": "a"}, {"X-API-Key": "b"}, {"X-API-Key": "a"}},
+			want:    []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:    "subject",
+			key:     KeySubject,
+			headers: []map[string]string{{"X-Subject": "alice"}, {"X-Subject": "bob"}, {}, {}},
+			want:    []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := newTestApp(t, Rule{Prefix: "/", Key: tc.key, Limit: 1, Period: time.Hour})
+
+			for i, headers := range tc.headers {
+				req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+				for k, v := range headers {
+					req.Header.Set(k, v)
+				}
+				resp, err := app.Test(req)
+				require.NoError(t, err)
+				assert.Equal(t, tc.want[i], resp.StatusCode, i)
+			}
+		})
+	}
+}
+
+func TestNew_InvalidRule(t *testing.T) {
+	testCases := []Rule{
+		{Prefix: "posts", Limit: 1, Period: time.Second},
+		{Prefix: "/posts", Period: time.Second},
+		{Prefix: "/posts", Limit: 1},
+		{Prefix: "/posts", Limit: 1, Period: time.Second, Key: "cookie"},
+	}
+
+	for _, rule := range testCases {
+		_, err := New(Config{Rules: []Rule{rule}}, NewMemoryStore())
+		assert.Error(t, err)
+	}
+}
+
+func TestLimiter_Update(t *testing.T) {
+	rule := Rule{Name: "posts", Prefix: "/posts", Limit: 1, Period: time.Minute}
+	l, err := New(Config{Enabled: true, Rules: []Rule{rule}}, NewMemoryStore())
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/posts", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+	status := func() int {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/posts", nil))
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, status())
+	assert.Equal(t, http.StatusTooManyRequests, status())
+
+	require.Error(t, l.Update(Config{Enabled: true, Rules: []Rule{{Prefix: "/posts"}}}))
+	assert.Equal(t, http.StatusTooManyRequests, status(), "invalid rules are not applied")
+
+	require.NoError(t, l.Update(Config{Enabled: false, Rules: []Rule{rule}}))
+	assert.Equal(t, http.StatusOK, status())
+
+	rule.Name = "posts_v2"
+	require.NoError(t, l.Update(Config{Enabled: true, Rules: []Rule{rule}}))
+	assert.Equal(t, http.StatusOK, status(), "renamed rule starts with a full bucket")
+	assert.Equal(t, http.StatusTooManyRequests, status())
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisConfig struct {
+	Addr     string `mapstructure:"addr" validate:"omitempty,hostname_port"`
+	Password string `mapstructure:"password" secret:"true"`
+	DB       int    `mapstructure:"db" validate:"gte=0"`
+}
+
+// takeScript атомарно пополняет корзину по прошедшему времени и списывает
+// токен. Время передается клиентом, ключ живет, пока корзина не заполнится.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+	tokens = capacity
+	ts = now
+end
+
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * rate)
+	ts = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore хранит корзины в Redis, чтобы лимиты были общими для всех
+// экземпляров шлюза.
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{
+		client: client,
+	}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, b Bucket) (bool, float64, error) {
+	now := time.Now().UnixMilli()
+	ratePerMs := b.Rate / 1000
+
+	res, err := takeScript.Run(ctx, s.client, []string{key}, b.Capacity, ratePerMs, now).Slice()
+	if err != nil {
+		return false, 0, errors.Wrap(err, "run take script")
+	}
+	if len(res) != 2 {
+		return false, 0, errors.Errorf("unexpected take script result %v", res)
+	}
+
+	allowed, _ := res[0].(int64)
+	raw, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return false, 0, errors.Wrap(err, "parse tokens")
+	}
+	return allowed == 1, tokens, nil
+}
+
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return errors.Wrap(s.client.Ping(ctx).Err(), "redis ping")
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+)
+
+type APIKeyRepo struct {
+	mu     sync.RWMutex
+	keys   map[uint64]models.APIKeyDTO
+	byHash map[string]uint64
+	lastID uint64
+}
+
+func NewAPIKeyProvider() *APIKeyRepo {
+	return &APIKeyRepo{
+		keys:   make(map[uint64]models.APIKeyDTO),
+		byHash: make(map[string]uint64),
+	}
+}
+
+func (r *APIKeyRepo) ListAPIKeys(ctx context.Context) ([]models.APIKeyDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]models.APIKeyDTO, 0, len(r.keys))
+	for _, k := range r.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func (r *APIKeyRepo) GetAPIKey(ctx context.Context, id uint64) (*models.APIKeyDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if k, ok := r.keys[id]; ok {
+		return &k, nil
+	}
+	return nil, apperr.ErrNotFound
+}
+
+func (r *APIKeyRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKeyDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id, ok := r.byHash[hash]; ok {
+		k := r.keys[id]
+		return &k, nil
+	}
+	return nil, apperr.ErrNotFound
+}
+
+func (r *APIKeyRepo) CreateAPIKey(ctx context.Context, key models.APIKeyDTO) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	key.ID = r.lastID
+	key.CreatedAt = time.Now().UTC()
+	r.keys[key.ID] = key
+	r.byHash[key.Hash] = key.ID
+	return key.ID, nil
+}
+
+// ReplaceAPIKeyHash заменяет хеш ключа при ротации, прежний ключ перестает
+// действовать сразу.
+func (r *APIKeyRepo) ReplaceAPIKeyHash(ctx context.Context, id uint64, hash, prefix string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.keys[id]
+	if !ok {
+		return apperr.ErrNotFound
+	}
+	delete(r.byHash, k.Hash)
+	k.Hash = hash
+	k.Prefix = prefix
+	r.keys[id] = k
+	r.byHash[hash] = id
+	return nil
+}
+
+func (r *APIKeyRepo) RevokeAPIKey(ctx context.Context, id uint64, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.keys[id]
+	if !ok {
+		return apperr.ErrNotFound
+	}
+	if k.RevokedAt == nil {
+		k.RevokedAt = &at
+		r.keys[id] = k
+	}
+	return nil
+}
+
+func (r *APIKeyRepo) TouchAPIKey(ctx context.Context, id uint64, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.keys[id]
+	if !ok {
+		return apperr.ErrNotFound
+	}
+	k.LastUsedAt = &at
+	r.keys[id] = k
+	return nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"slices"
+
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+)
+
+// ListMigrations возвращает примененные миграции в порядке версий.
+func (b *PostRepo) ListMigrations(ctx context.Context) ([]models.MigrationDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	migrations := make([]models.MigrationDTO, 0, len(b.migrations))
+	for _, m := range b.migrations {
+		m.PostIDs = slices.Clone(m.PostIDs)
+		migrations = append(migrations, m)
+	}
+	slices.SortFunc(migrations, func(a, b models.MigrationDTO) int { return a.Version - b.Version })
+	return migrations, nil
+}
+
+// SaveMigration отмечает миграцию примененной.
+func (b *PostRepo) SaveMigration(ctx context.Context, migration models.MigrationDTO) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	migration.PostIDs = slices.Clone(migration.PostIDs)
+	b.migrations[migration.Version] = migration
+	return nil
+}
+
+// DeleteMigration отмечает миграцию откаченной.
+func (b *PostRepo) DeleteMigration(ctx context.Context, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.migrations[version]; !ok {
+		return apperr.ErrNotFound
+	}
+	delete(b.migrations, version)
+	return nil
+}
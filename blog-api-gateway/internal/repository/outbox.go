@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+)
+
+// outbox защищается мьютексом PostRepo.
+type outbox struct {
+	lastID  uint64
+	entries map[uint64]models.OutboxEntryDTO
+	signal  chan struct{}
+}
+
+func newOutbox() outbox {
+	return outbox{
+		entries: make(map[uint64]models.OutboxEntryDTO),
+		signal:  make(chan struct{}, 1),
+	}
+}
+
+func (o *outbox) add(event models.PostEvent) {
+	o.lastID++
+	o.entries[o.lastID] = models.OutboxEntryDTO{
+		ID:            o.lastID,
+		Event:         event,
+		Status:        models.OutboxPending,
+		CreatedAt:     event.OccurredAt,
+		NextAttemptAt: event.OccurredAt,
+	}
+	o.notify()
+}
+
+func (o *outbox) notify() {
+	select {
+	case o.signal <- struct{}{}:
+	default:
+	}
+}
+
+// OutboxSignal срабатывает после появления новых записей в outbox.
+func (b *PostRepo) OutboxSignal() <-chan struct{} {
+	return b.outbox.signal
+}
+
+// PendingOutbox возвращает до limit ожидающих записей, время доставки
+// которых наступило, в порядке их создания.
+func (b *PostRepo) PendingOutbox(limit int, now time.Time) ([]models.OutboxEntryDTO, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var entries []models.OutboxEntryDTO
+	for _, e := range b.outbox.entries {
+		if e.Status == models.OutboxPending && !e.NextAttemptAt.After(now) {
+			entries = append(entries, e)
+		}
+	}
+	sortOutbox(entries)
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+func (b *PostRepo) ListOutbox(ctx context.Context, status models.OutboxStatus) ([]models.OutboxEntryDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	entries := make([]models.OutboxEntryDTO, 0)
+	for _, e := range b.outbox.entries {
+		if status == "" || e.Status == status {
+			entries = append(entries, e)
+		}
+	}
+	sortOutbox(entries)
+	return entries, nil
+}
+
+// CountOutbox возвращает число записей outbox по статусам: ожидающих
+// доставки и отложенных до ручного повтора.
+func (b *PostRepo) CountOutbox() map[models.OutboxStatus]int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	counts := make(map[models.OutboxStatus]int)
+	for _, e := range b.outbox.entries {
+		counts[e.Status]++
+	}
+	return counts
+}
+
+// CompleteOutbox удаляет доставленную запись.
+func (b *PostRepo) CompleteOutbox(id uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.outbox.entries[id]; !ok {
+		return apperr.ErrNotFound
+	}
+	delete(b.outbox.entries, id)
+	return nil
+}
+
+// FailOutbox фиксирует неудачную попытку и приемники delivered, уже
+// получившие событие. Запись откладывается до retryAt или, если failed,
+// переводится в статус failed до ручного повтора.
+func (b *PostRepo) FailOutbox(id uint64, delivered []string, reason string, retryAt time.Time, failed bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.outbox.entries[id]
+	if !ok {
+		return apperr.ErrNotFound
+	}
+	e.Attempts++
+	e.Delivered = delivered
+	e.LastError = reason
+	e.NextAttemptAt = retryAt
+	if failed {
+		e.Status = models.OutboxFailed
+	}
+	b.outbox.entries[id] = e
+	return nil
+}
+
+// RetryOutbox возвращает запись в очередь на доставку в приемники, еще не
+// получившие событие.
+func (b *PostRepo) RetryOutbox(ctx context.Context, id uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.outbox.entries[id]
+	if !ok {
+		return apperr.ErrNotFound
+	}
+	e.Status = models.OutboxPending
+	e.Attempts = 0
+	e.NextAttemptAt = time.Now().UTC()
+	b.outbox.entries[id] = e
+	b.outbox.notify()
+	return nil
+}
+
+func sortOutbox(entries []models.OutboxEntryDTO) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+}
@@ -1,21 +1,36 @@
 package repository
 
 import (
+	"context"
+	"slices"
 	"sync"
 
 	"github.com/mtvy/blog-api-gateway/internal/apperr"
 	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/internal/tracing"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+var tracer = otel.Tracer("github.com/mtvy/blog-api-gateway/internal/repository")
+
+// PostRepo хранит посты, outbox событий об их изменениях и примененные
+// миграции. Запись в outbox выполняется под той же блокировкой, что и
+// изменение поста.
 type PostRepo struct {
-	mu     sync.RWMutex
-	posts  map[uint64]models.PostDTO
-	lastID uint64
+	mu         sync.RWMutex
+	posts      map[uint64]models.PostDTO
+	lastID     uint64
+	outbox     outbox
+	migrations map[int]models.MigrationDTO
 }
 
 func NewPostProvider() *PostRepo {
 	return &PostRepo{
-		posts: make(map[uint64]models.PostDTO),
+		posts:      make(map[uint64]models.PostDTO),
+		outbox:     newOutbox(),
+		migrations: make(map[int]models.MigrationDTO),
 	}
 }
 
@@ -27,64 +42,208 @@ func (b *PostRepo) get(id uint64) (*models.PostDTO, bool) {
 	return &post, ok
 }
 
-func (b *PostRepo) set(post models.PostDTO) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+func (b *PostRepo) ListPost(ctx context.Context) (_ []models.PostDTO, err error) {
+	_, span := tracer.Start(ctx, "PostRepo.ListPost")
+	defer func() { tracing.End(span, err) }()
 
-	b.posts[post.ID] = post
-}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-func (b *PostRepo) incID() uint64 {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 
-	b.lastID += 1
-	return b.lastID
+	posts := make([]models.PostDTO, 0, len(b.posts))
+	for _, post := range b.posts {
+		posts = append(posts, post)
+	}
+	return posts, nil
 }
 
-func (b *PostRepo) delete(id uint64) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+// ListPostPage возвращает до limit постов с идентификаторами больше afterID
+// в порядке идентификаторов.
+func (b *PostRepo) ListPostPage(ctx context.Context, afterID uint64, limit int) (_ []models.PostDTO, err error) {
+	_, span := tracer.Start(ctx, "PostRepo.ListPostPage")
+	span.SetAttributes(attribute.Int64("post.after_id", int64(afterID)))
+	defer func() { tracing.End(span, err) }()
 
-	delete(b.posts, id)
-}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-func (b *PostRepo) ListPost() ([]models.PostDTO, error) {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	posts := make([]models.PostDTO, 0, len(b.posts))
-	for _, post := range b.posts {
-		posts = append(posts, post)
+	var ids []uint64
+	for id := range b.posts {
+		if id > afterID {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	posts := make([]models.PostDTO, 0, len(ids))
+	for _, id := range ids {
+		posts = append(posts, b.posts[id])
 	}
 	return posts, nil
 }
 
-func (b *PostRepo) GetPost(id uint64) (*models.PostDTO, error) {
+// Ping проверяет, что хранилище не заблокировано дольше срока ctx.
+func (b *PostRepo) Ping(ctx context.Context) error {
+	locked := make(chan struct{})
+	go func() {
+		b.mu.RLock()
+		b.mu.RUnlock()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *PostRepo) CountPosts() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.posts)
+}
+
+func (b *PostRepo) GetPost(ctx context.Context, id uint64) (post *models.PostDTO, err error) {
+	_, span := tracer.Start(ctx, "PostRepo.GetPost")
+	span.SetAttributes(attribute.Int64("post.id", int64(id)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if post, ok := b.get(id); ok {
 		return post, nil
 	}
 	return nil, apperr.ErrNotFound
 }
 
-func (b *PostRepo) CreatePost(post models.PostDTO) (uint64, error) {
-	post.ID = b.incID()
-	b.set(post)
+func (b *PostRepo) CreatePost(ctx context.Context, post models.PostDTO) (_ uint64, err error) {
+	_, span := tracer.Start(ctx, "PostRepo.CreatePost")
+	defer func() { tracing.End(span, err) }()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID += 1
+	post.ID = b.lastID
+	b.posts[post.ID] = post
+	b.outbox.add(models.NewPostEvent(models.PostCreated, post))
 	return post.ID, nil
 }
 
-func (b *PostRepo) UpdatePost(post models.PostDTO) error {
-	if _, ok := b.get(post.ID); !ok {
+// ImportPosts сохраняет пакет постов под одной блокировкой и записывает
+// событие о каждом в outbox. mode определяет, что делать с занятым
+// идентификатором; в режиме ImportFail при занятом или повторяющемся
+// идентификаторе ничего не сохраняется и возвращается apperr.ErrConflict.
+func (b *PostRepo) ImportPosts(ctx context.Context, posts []models.PostDTO, mode models.ImportMode) (report models.ImportReportDTO, err error) {
+	_, span := tracer.Start(ctx, "PostRepo.ImportPosts")
+	span.SetAttributes(attribute.Int("posts.count", len(posts)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch mode {
+	case models.ImportFail:
+		ids := make(map[uint64]bool, len(posts))
+		for _, post := range posts {
+			if _, ok := b.posts[post.ID]; ok || ids[post.ID] {
+				return report, errors.Wrapf(apperr.ErrConflict, "post %d", post.ID)
+			}
+			ids[post.ID] = true
+		}
+	case models.ImportRemap:
+		report.Remapped = make(map[uint64]uint64, len(posts))
+	}
+
+	for _, post := range posts {
+		report.Processed++
+		if mode == models.ImportRemap {
+			b.lastID += 1
+			if post.ID != 0 {
+				report.Remapped[post.ID] = b.lastID
+			}
+			post.ID = b.lastID
+		}
+
+		_, exists := b.posts[post.ID]
+		if exists && mode == models.ImportSkip {
+			report.Skipped++
+			continue
+		}
+		b.posts[post.ID] = post
+		if post.ID > b.lastID {
+			b.lastID = post.ID
+		}
+		if exists {
+			report.Updated++
+			b.outbox.add(models.NewPostEvent(models.PostUpdated, post))
+		} else {
+			report.Created++
+			b.outbox.add(models.NewPostEvent(models.PostCreated, post))
+		}
+	}
+	return report, nil
+}
+
+func (b *PostRepo) UpdatePost(ctx context.Context, post models.PostDTO) (err error) {
+	_, span := tracer.Start(ctx, "PostRepo.UpdatePost")
+	span.SetAttributes(attribute.Int64("post.id", int64(post.ID)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.posts[post.ID]; !ok {
 		return apperr.ErrNotFound
 	}
-	b.set(post)
+	b.posts[post.ID] = post
+	b.outbox.add(models.NewPostEvent(models.PostUpdated, post))
 	return nil
 }
 
-func (b *PostRepo) DeletePost(id uint64) error {
-	if _, ok := b.get(id); !ok {
+func (b *PostRepo) DeletePost(ctx context.Context, id uint64) (err error) {
+	_, span := tracer.Start(ctx, "PostRepo.DeletePost")
+	span.SetAttributes(attribute.Int64("post.id", int64(id)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	post, ok := b.posts[id]
+	if !ok {
 		return apperr.ErrNotFound
 	}
-	b.delete(id)
+	delete(b.posts, id)
+	b.outbox.add(models.NewPostEvent(models.PostDeleted, post))
 	return nil
 }
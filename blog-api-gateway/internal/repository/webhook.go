@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+)
+
+type WebhookRepo struct {
+	mu           sync.RWMutex
+	webhooks     map[uint64]models.WebhookDTO
+	lastID       uint64
+	deliveries   map[uint64][]models.DeliveryDTO
+	lastDelivery uint64
+	deadLetters  []models.DeadLetterDTO
+	lastDead     uint64
+	logSize      int
+}
+
+// NewWebhookProvider хранит не более logSize доставок на вебхук
+// и не более logSize записей в списке недоставленных событий.
+func NewWebhookProvider(logSize int) *WebhookRepo {
+	return &WebhookRepo{
+		webhooks:   make(map[uint64]models.WebhookDTO),
+		deliveries: make(map[uint64][]models.DeliveryDTO),
+		logSize:    logSize,
+	}
+}
+
+func (r *WebhookRepo) ListWebhooks(ctx context.Context) ([]models.WebhookDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhooks := make([]models.WebhookDTO, 0, len(r.webhooks))
+	for _, w := range r.webhooks {
+		webhooks = append(webhooks, w)
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+func (r *WebhookRepo) GetWebhook(ctx context.Context, id uint64) (*models.WebhookDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if w, ok := r.webhooks[id]; ok {
+		return &w, nil
+	}
+	return nil, apperr.ErrNotFound
+}
+
+func (r *WebhookRepo) CreateWebhook(ctx context.Context, webhook models.WebhookDTO) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	webhook.ID = r.lastID
+	webhook.CreatedAt = time.Now().UTC()
+	r.webhooks[webhook.ID] = webhook
+	return webhook.ID, nil
+}
+
+func (r *WebhookRepo) UpdateWebhook(ctx context.Context, webhook models.WebhookDTO) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.webhooks[webhook.ID]
+	if !ok {
+		return apperr.ErrNotFound
+	}
+	webhook.CreatedAt = old.CreatedAt
+	if webhook.Secret == "" {
+		webhook.Secret = old.Secret
+	}
+	r.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+func (r *WebhookRepo) DeleteWebhook(ctx context.Context, id uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[id]; !ok {
+		return apperr.ErrNotFound
+	}
+	delete(r.webhooks, id)
+	delete(r.deliveries, id)
+	return nil
+}
+
+func (r *WebhookRepo) AddDelivery(delivery models.DeliveryDTO) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastDelivery++
+	delivery.ID = r.lastDelivery
+	r.deliveries[delivery.WebhookID] = appendBounded(r.deliveries[delivery.WebhookID], delivery, r.logSize)
+}
+
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, webhookID uint64) ([]models.DeliveryDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.webhooks[webhookID]; !ok {
+		return nil, apperr.ErrNotFound
+	}
+	deliveries := make([]models.DeliveryDTO, len(r.deliveries[webhookID]))
+	copy(deliveries, r.deliveries[webhookID])
+	return deliveries, nil
+}
+
+func (r *WebhookRepo) AddDeadLetter(letter models.DeadLetterDTO) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastDead++
+	letter.ID = r.lastDead
+	r.deadLetters = appendBounded(r.deadLetters, letter, r.logSize)
+}
+
+func (r *WebhookRepo) ListDeadLetters(ctx context.Context) ([]models.DeadLetterDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	letters := make([]models.DeadLetterDTO, len(r.deadLetters))
+	copy(letters, r.deadLetters)
+	return letters, nil
+}
+
+func appendBounded[T any](items []T, item T, limit int) []T {
+	items = append(items, item)
+	if limit > 0 && len(items) > limit {
+		items = items[len(items)-limit:]
+	}
+	return items
+}
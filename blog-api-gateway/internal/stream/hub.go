@@ -0,0 +1,180 @@
+package stream
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+)
+
+type Message struct {
+	Seq   uint64
+	Event models.PostEvent
+}
+
+// Filter ограничивает события подписки. Пустой фильтр пропускает все события.
+type Filter struct {
+	Authors []string `json:"authors"`
+	PostIDs []uint64 `json:"post_ids"`
+}
+
+func (f Filter) Match(event models.PostEvent) bool {
+	if len(f.Authors) > 0 && !slices.Contains(f.Authors, event.Post.Author) {
+		return false
+	}
+	if len(f.PostIDs) > 0 && !slices.Contains(f.PostIDs, event.PostID) {
+		return false
+	}
+	return true
+}
+
+// Hub рассылает изменения постов подписчикам и хранит ограниченный буфер
+// последних сообщений для возобновления по Last-Event-ID.
+type Hub struct {
+	mu         sync.Mutex
+	seq        uint64
+	replay     []Message
+	replaySize int
+	bufferSize int
+	subs       map[*Subscription]struct{}
+	closed     bool
+}
+
+func NewHub(replaySize, bufferSize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	return &Hub{
+		replaySize: replaySize,
+		bufferSize: bufferSize,
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish никогда не блокирует вызывающего: подписчик, чей буфер переполнен,
+// отключается и может переподключиться с Last-Event-ID.
+func (h *Hub) Publish(event models.PostEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	msg := Message{Seq: h.seq, Event: event}
+
+	if h.replaySize > 0 {
+		if len(h.replay) == h.replaySize {
+			h.replay = h.replay[1:]
+		}
+		h.replay = append(h.replay, msg)
+	}
+
+	for sub := range h.subs {
+		if !sub.match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			h.drop(sub, true)
+		}
+	}
+}
+
+// Subscribe регистрирует подписчика и возвращает сообщения из буфера
+// с номером больше lastSeq, прошедшие фильтр.
+func (h *Hub) Subscribe(filter Filter, lastSeq uint64) (*Subscription, []Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{
+		hub:    h,
+		ch:     make(chan Message, h.bufferSize),
+		filter: filter,
+	}
+	if h.closed {
+		close(sub.ch)
+		return sub, nil
+	}
+	h.subs[sub] = struct{}{}
+
+	if lastSeq == 0 {
+		return sub, nil
+	}
+
+	var missed []Message
+	for _, msg := range h.replay {
+		if msg.Seq > lastSeq && filter.Match(msg.Event) {
+			missed = append(missed, msg)
+		}
+	}
+	return sub, missed
+}
+
+// Close отключает всех подписчиков, чтобы потоковые ответы завершились при
+// остановке сервера. Новые подписки сразу закрываются.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		h.drop(sub, false)
+	}
+}
+
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subs)
+}
+
+func (h *Hub) drop(sub *Subscription, lagged bool) {
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+	delete(h.subs, sub)
+	sub.lagged = lagged
+	close(sub.ch)
+}
+
+type Subscription struct {
+	hub    *Hub
+	ch     chan Message
+	fmu    sync.RWMutex
+	filter Filter
+	lagged bool
+}
+
+// C закрывается при отписке или при отключении медленного подписчика.
+func (s *Subscription) C() <-chan Message {
+	return s.ch
+}
+
+func (s *Subscription) SetFilter(filter Filter) {
+	s.fmu.Lock()
+	defer s.fmu.Unlock()
+
+	s.filter = filter
+}
+
+// Lagged сообщает, что подписка была закрыта из-за переполнения буфера.
+func (s *Subscription) Lagged() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	return s.lagged
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.drop(s, false)
+}
+
+func (s *Subscription) match(event models.PostEvent) bool {
+	s.fmu.RLock()
+	defer s.fmu.RUnlock()
+
+	return s.filter.Match(event)
+}
@@ -0,0 +1,110 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEvent(id uint64, author string) models.PostEvent {
+	return models.NewPostEvent(models.PostCreated, models.PostDTO{ID: id, Author: author})
+}
+
+func TestHub_Filter(t *testing.T) {
+	testCases := []struct {
+		name   string
+		filter Filter
+		want   []uint64
+	}{
+		{
+			name: "all",
+			want: []uint64{1, 2, 3},
+		},
+		{
+			name:   "by_author",
+			filter: Filter{Authors: []string{"Author 2"}},
+			want:   []uint64{2},
+		},
+		{
+			name:   "by_post_id",
+			filter: Filter{PostIDs: []uint64{1, 3}},
+			want:   []uint64{1, 3},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hub := NewHub(0, 10)
+			sub, _ := hub.Subscribe(tc.filter, 0)
+			defer sub.Close()
+
+			hub.Publish(newEvent(1, "Author 1"))
+			hub.Publish(newEvent(2, "Author 2"))
+			hub.Publish(newEvent(3, "Author 3"))
+
+			var got []uint64
+			for len(sub.C()) > 0 {
+				msg := <-sub.C()
+				got = append(got, msg.Event.PostID)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestHub_Replay(t *testing.T) {
+	hub := NewHub(2, 10)
+	for i := uint64(1); i <= 3; i++ {
+		hub.Publish(newEvent(i, "Author"))
+	}
+
+	sub, missed := hub.Subscribe(Filter{}, 1)
+	defer sub.Close()
+
+	require.Len(t, missed, 2)
+	assert.Equal(t, uint64(2), missed[0].Seq)
+	assert.Equal(t, uint64(3), missed[1].Seq)
+
+	sub, missed = hub.Subscribe(Filter{}, 0)
+	defer sub.Close()
+	assert.Empty(t, missed)
+}
+
+func TestHub_SlowConsumer(t *testing.T) {
+	hub := NewHub(0, 1)
+	slow, _ := hub.Subscribe(Filter{}, 0)
+	fast, _ := hub.Subscribe(Filter{}, 0)
+	defer fast.Close()
+
+	hub.Publish(newEvent(1, "Author"))
+	<-fast.C()
+	hub.Publish(newEvent(2, "Author"))
+
+	<-slow.C()
+	_, ok := <-slow.C()
+	assert.False(t, ok)
+	assert.True(t, slow.Lagged())
+	assert.Equal(t, 1, hub.Subscribers())
+
+	msg := <-fast.C()
+	assert.Equal(t, uint64(2), msg.Seq)
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub(0, 1)
+	sub, _ := hub.Subscribe(Filter{}, 0)
+
+	hub.Close()
+	_, ok := <-sub.C()
+	assert.False(t, ok)
+	assert.False(t, sub.Lagged())
+	assert.Zero(t, hub.Subscribers())
+
+	late, _ := hub.Subscribe(Filter{}, 0)
+	_, ok = <-late.C()
+	assert.False(t, ok, "subscriptions after close are closed at once")
+	late.Close()
+}
@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/httpx"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/mtvy/blog-api-gateway/internal/tracing"
+
+var tracer = otel.Tracer(instrumentation)
+
+// Handle открывает серверный span запроса, продолжая трассу из traceparent,
+// и сохраняет его контекст в c.UserContext(). После обработки span получает
+// имя по шаблону маршрута, код ответа и ошибку обработчика; ответы 5xx
+// отмечаются статусом Error.
+func Handle(c *fiber.Ctx) error {
+	ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), HeaderCarrier{&c.Request().Header})
+	ctx, span := tracer.Start(ctx, c.Method(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(c.Method()),
+			semconv.URLPath(c.Path()),
+			semconv.ClientAddress(c.IP()),
+		),
+	)
+	defer span.End()
+	c.SetUserContext(ctx)
+
+	route, err := httpx.Next(c)
+	if route != "" {
+		span.SetName(c.Method() + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route))
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	status := c.Response().StatusCode()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	return nil
+}
+
+// Inject записывает контекст трассы ctx в заголовки исходящего запроса.
+func Inject(ctx context.Context, h *fasthttp.RequestHeader) {
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier{h})
+}
+
+// HeaderCarrier адаптирует заголовки fasthttp к propagation.TextMapCarrier.
+type HeaderCarrier struct {
+	Header *fasthttp.RequestHeader
+}
+
+func (h HeaderCarrier) Get(key string) string {
+	return string(h.Header.Peek(key))
+}
+
+func (h HeaderCarrier) Set(key, value string) {
+	h.Header.Set(key, value)
+}
+
+func (h HeaderCarrier) Keys() []string {
+	var keys []string
+	h.Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
@@ -0,0 +1,111 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/mtvy/blog-api-gateway/internal/gateway"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/internal/repository"
+	"github.com/mtvy/blog-api-gateway/internal/tracing"
+	"github.com/mtvy/blog-api-gateway/internal/usecase"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	traceparent = "00-" + traceID + "-00f067aa0ba902b7-01"
+)
+
+// Трассировщики пакетов привязываются к первому глобальному провайдеру,
+// поэтому провайдер задается один раз на все тесты.
+var exporter = tracetest.NewInMemoryExporter()
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	if _, err := tracing.Setup(tracing.Config{}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestHandle_Spans(t *testing.T) {
+	repo := repository.NewPostProvider()
+	id, err := repo.CreatePost(context.Background(), models.PostDTO{Title: "Title", Author: "Author"})
+	require.NoError(t, err)
+	exporter.Reset()
+	uc := usecase.NewPostProvider(repo)
+
+	app := fiber.New()
+	app.Use(tracing.Handle)
+	app.Get("/posts/:id", func(c *fiber.Ctx) error {
+		id, _ := strconv.ParseUint(c.Params("id"), 10, 64)
+		post, err := uc.GetPost(c.UserContext(), id)
+		if err != nil {
+			return fiber.NewError(http.StatusNotFound)
+		}
+		return c.JSON(post)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/"+strconv.FormatUint(id, 10), nil)
+	req.Header.Set("traceparent", traceparent)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	spans := exporter.GetSpans().Snapshots()
+	require.Len(t, spans, 3)
+	names := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spans {
+		assert.Equal(t, traceID, span.SpanContext().TraceID().String())
+		names[span.Name()] = span
+	}
+	require.Contains(t, names, "GET /posts/:id")
+	require.Contains(t, names, "Usecase.GetPost")
+	require.Contains(t, names, "PostRepo.GetPost")
+	assert.Equal(t, names["GET /posts/:id"].SpanContext().SpanID(), names["Usecase.GetPost"].Parent().SpanID())
+	assert.Equal(t, names["Usecase.GetPost"].SpanContext().SpanID(), names["PostRepo.GetPost"].Parent().SpanID())
+}
+
+func TestHandle_PropagatesToUpstream(t *testing.T) {
+	exporter.Reset()
+
+	var seen string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+
+	g, err := gateway.New(gateway.Config{Routes: []gateway.Route{{
+		Prefix:    "/comments",
+		Upstreams: []gateway.UpstreamConfig{{URL: upstream.URL}},
+	}}})
+	require.NoError(t, err)
+	app := fiber.New()
+	app.Use(tracing.Handle)
+	g.Mount(app)
+
+	req := httptest.NewRequest(http.MethodGet, "/comments/1", nil)
+	req.Header.Set("traceparent", traceparent)
+	_, err = app.Test(req)
+	require.NoError(t, err)
+
+	var proxySpan sdktrace.ReadOnlySpan
+	for _, span := range exporter.GetSpans().Snapshots() {
+		if span.Name() == "proxy /comments" {
+			proxySpan = span
+		}
+	}
+	require.NotNil(t, proxySpan)
+	assert.Equal(t, "00-"+traceID+"-"+proxySpan.SpanContext().SpanID().String()+"-01", seen)
+}
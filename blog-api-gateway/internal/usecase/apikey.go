@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	apiKeyPrefix    = "bk_"
+	apiKeyPrefixLen = len(apiKeyPrefix) + 8
+)
+
+type apiKeyProvider interface {
+	ListAPIKeys(ctx context.Context) ([]models.APIKeyDTO, error)
+	GetAPIKey(ctx context.Context, id uint64) (*models.APIKeyDTO, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKeyDTO, error)
+	CreateAPIKey(ctx context.Context, key models.APIKeyDTO) (uint64, error)
+	ReplaceAPIKeyHash(ctx context.Context, id uint64, hash, prefix string) error
+	RevokeAPIKey(ctx context.Context, id uint64, at time.Time) error
+	TouchAPIKey(ctx context.Context, id uint64, at time.Time) error
+}
+
+type APIKeyUsecase struct {
+	apiKeyRepo apiKeyProvider
+}
+
+func NewAPIKeyProvider(apiKeyRepo apiKeyProvider) *APIKeyUsecase {
+	return &APIKeyUsecase{
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+func (u *APIKeyUsecase) ListAPIKeys(ctx context.Context) ([]models.APIKeyDTO, error) {
+	return u.apiKeyRepo.ListAPIKeys(ctx)
+}
+
+// IssueAPIKey выпускает ключ. Открытый ключ возвращается только здесь.
+func (u *APIKeyUsecase) IssueAPIKey(ctx context.Context, key models.APIKeyDTO) (*models.IssuedAPIKeyDTO, error) {
+	raw, err := newAPIKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "generate key")
+	}
+	return u.create(ctx, key, raw)
+}
+
+// Bootstrap регистрирует ключ администратора из конфигурации, чтобы выпустить
+// первые ключи.
+func (u *APIKeyUsecase) Bootstrap(ctx context.Context, raw string) error {
+	_, err := u.create(ctx, models.APIKeyDTO{
+		Name:   "bootstrap",
+		Scopes: []string{models.ScopeAdmin},
+	}, raw)
+	return err
+}
+
+// RotateAPIKey выпускает новый ключ с теми же правами, прежний перестает
+// действовать.
+func (u *APIKeyUsecase) RotateAPIKey(ctx context.Context, id uint64) (*models.IssuedAPIKeyDTO, error) {
+	key, err := u.apiKeyRepo.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, errors.Wrapf(apperr.ErrRevoked, "api key %d", id)
+	}
+
+	raw, err := newAPIKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "generate key")
+	}
+	if err := u.apiKeyRepo.ReplaceAPIKeyHash(ctx, id, hashAPIKey(raw), raw[:apiKeyPrefixLen]); err != nil {
+		return nil, err
+	}
+
+	key, err = u.apiKeyRepo.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.IssuedAPIKeyDTO{APIKeyDTO: *key, Key: raw}, nil
+}
+
+func (u *APIKeyUsecase) RevokeAPIKey(ctx context.Context, id uint64) error {
+	return u.apiKeyRepo.RevokeAPIKey(ctx, id, time.Now().UTC())
+}
+
+// Authenticate находит действующий ключ и отмечает время его использования.
+func (u *APIKeyUsecase) Authenticate(ctx context.Context, raw string) (*models.APIKeyDTO, error) {
+	key, err := u.apiKeyRepo.GetAPIKeyByHash(ctx, hashAPIKey(raw))
+	if err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return nil, apperr.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if !key.Valid(now) {
+		return nil, apperr.ErrUnauthorized
+	}
+	if err := u.apiKeyRepo.TouchAPIKey(ctx, key.ID, now); err != nil {
+		return nil, err
+	}
+	key.LastUsedAt = &now
+	return key, nil
+}
+
+func (u *APIKeyUsecase) create(ctx context.Context, key models.APIKeyDTO, raw string) (*models.IssuedAPIKeyDTO, error) {
+	if len(raw) < apiKeyPrefixLen {
+		return nil, errors.New("api key is too short")
+	}
+	key.Hash = hashAPIKey(raw)
+	key.Prefix = raw[:apiKeyPrefixLen]
+
+	id, err := u.apiKeyRepo.CreateAPIKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	created, err := u.apiKeyRepo.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.IssuedAPIKeyDTO{APIKeyDTO: *created, Key: raw}, nil
+}
+
+func newAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(b), nil
+}
+
+// hashAPIKey - ключи случайные и длинные, поэтому достаточно SHA-256 без соли.
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
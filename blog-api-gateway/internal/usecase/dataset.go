@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"context"
+	"io"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/pkg/errors"
+)
+
+type datasetProvider interface {
+	CountPosts() int
+	ListPostPage(ctx context.Context, afterID uint64, limit int) ([]models.PostDTO, error)
+	ImportPosts(ctx context.Context, posts []models.PostDTO, mode models.ImportMode) (models.ImportReportDTO, error)
+}
+
+type DatasetUsecase struct {
+	repo datasetProvider
+}
+
+func NewDatasetProvider(repo datasetProvider) *DatasetUsecase {
+	return &DatasetUsecase{
+		repo: repo,
+	}
+}
+
+// exportPageSize - сколько постов Export читает из хранилища за раз.
+const exportPageSize = 1000
+
+// CountPosts возвращает текущее число постов.
+func (u *DatasetUsecase) CountPosts() int {
+	return u.repo.CountPosts()
+}
+
+// Export передает посты в send в порядке идентификаторов, читая их из
+// хранилища страницами. Посты, измененные во время экспорта, попадают в него
+// в том виде, в каком их застала страница.
+func (u *DatasetUsecase) Export(ctx context.Context, send func(models.PostRecordDTO) error) error {
+	var afterID uint64
+	for {
+		posts, err := u.repo.ListPostPage(ctx, afterID, exportPageSize)
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			if err := send(models.NewPostRecord(post)); err != nil {
+				return err
+			}
+		}
+		if len(posts) < exportPageSize {
+			return nil
+		}
+		afterID = posts[len(posts)-1].ID
+	}
+}
+
+// importBatchSize - сколько постов Import сохраняет за раз и через сколько
+// сообщает о ходе импорта.
+const importBatchSize = 1000
+
+// Import читает посты из next, пока тот не вернет io.EOF, и сохраняет их
+// пакетами, поступая с занятыми идентификаторами согласно mode. progress
+// вызывается после каждого полного пакета. Ошибка next возвращается как
+// есть; пакеты, сохраненные до нее, остаются. В режиме ImportFail посты
+// сохраняются одним пакетом: при ошибке или занятом идентификаторе ничего не
+// сохраняется, а для занятого возвращается apperr.ErrConflict.
+func (u *DatasetUsecase) Import(ctx context.Context, next func() (models.PostRecordDTO, error), mode models.ImportMode, progress func(models.ImportReportDTO)) (models.ImportReportDTO, error) {
+	var report models.ImportReportDTO
+	if mode == models.ImportRemap {
+		report.Remapped = make(map[uint64]uint64)
+	}
+
+	batch := make([]models.PostDTO, 0, importBatchSize)
+	for {
+		post, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		batch = append(batch, post.ToDTO())
+
+		if mode == models.ImportFail || len(batch) < importBatchSize {
+			continue
+		}
+		if err := u.importBatch(ctx, batch, mode, &report); err != nil {
+			return report, err
+		}
+		batch = batch[:0]
+		if progress != nil {
+			progress(report)
+		}
+	}
+
+	if err := u.importBatch(ctx, batch, mode, &report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+func (u *DatasetUsecase) importBatch(ctx context.Context, batch []models.PostDTO, mode models.ImportMode, report *models.ImportReportDTO) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	saved, err := u.repo.ImportPosts(ctx, batch, mode)
+	if err != nil {
+		return err
+	}
+	report.Processed += saved.Processed
+	report.Created += saved.Created
+	report.Updated += saved.Updated
+	report.Skipped += saved.Skipped
+	for id, newID := range saved.Remapped {
+		report.Remapped[id] = newID
+	}
+	return nil
+}
+
+// Seed добавляет посты из next с новыми идентификаторами и возвращает их
+// число.
+func (u *DatasetUsecase) Seed(ctx context.Context, next func() (models.PostRecordDTO, error)) (int, error) {
+	report, err := u.Import(ctx, next, models.ImportRemap, nil)
+	return report.Created, err
+}
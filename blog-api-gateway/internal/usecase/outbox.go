@@ -0,0 +1,30 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+)
+
+type outboxProvider interface {
+	ListOutbox(ctx context.Context, status models.OutboxStatus) ([]models.OutboxEntryDTO, error)
+	RetryOutbox(ctx context.Context, id uint64) error
+}
+
+type OutboxUsecase struct {
+	outboxRepo outboxProvider
+}
+
+func NewOutboxProvider(outboxRepo outboxProvider) *OutboxUsecase {
+	return &OutboxUsecase{
+		outboxRepo: outboxRepo,
+	}
+}
+
+func (u *OutboxUsecase) ListOutbox(ctx context.Context, status models.OutboxStatus) ([]models.OutboxEntryDTO, error) {
+	return u.outboxRepo.ListOutbox(ctx, status)
+}
+
+func (u *OutboxUsecase) RetryOutbox(ctx context.Context, id uint64) error {
+	return u.outboxRepo.RetryOutbox(ctx, id)
+}
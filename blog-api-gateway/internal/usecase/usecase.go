@@ -1,13 +1,22 @@
 package usecase
 
-import "github.com/mtvy/blog-api-gateway/internal/models"
+import (
+	"context"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var tracer = otel.Tracer("github.com/mtvy/blog-api-gateway/internal/usecase")
 
 type postProvider interface {
-	ListPost() ([]models.PostDTO, error)
-	GetPost(id uint64) (*models.PostDTO, error)
-	CreatePost(post models.PostDTO) (uint64, error)
-	DeletePost(id uint64) error
-	UpdatePost(post models.PostDTO) error
+	ListPost(ctx context.Context) ([]models.PostDTO, error)
+	GetPost(ctx context.Context, id uint64) (*models.PostDTO, error)
+	CreatePost(ctx context.Context, post models.PostDTO) (uint64, error)
+	DeletePost(ctx context.Context, id uint64) error
+	UpdatePost(ctx context.Context, post models.PostDTO) error
 }
 
 type Usecase struct {
@@ -20,22 +29,40 @@ func NewPostProvider(postRepo postProvider) *Usecase {
 	}
 }
 
-func (u *Usecase) ListPost() ([]models.PostDTO, error) {
-	return u.postRepo.ListPost()
+func (u *Usecase) ListPost(ctx context.Context) (posts []models.PostDTO, err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.ListPost")
+	defer func() { tracing.End(span, err) }()
+
+	return u.postRepo.ListPost(ctx)
 }
 
-func (u *Usecase) GetPost(id uint64) (*models.PostDTO, error) {
-	return u.postRepo.GetPost(id)
+func (u *Usecase) GetPost(ctx context.Context, id uint64) (post *models.PostDTO, err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetPost")
+	span.SetAttributes(attribute.Int64("post.id", int64(id)))
+	defer func() { tracing.End(span, err) }()
+
+	return u.postRepo.GetPost(ctx, id)
 }
 
-func (u *Usecase) CreatePost(post models.PostDTO) (uint64, error) {
-	return u.postRepo.CreatePost(post)
+func (u *Usecase) CreatePost(ctx context.Context, post models.PostDTO) (id uint64, err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.CreatePost")
+	defer func() { tracing.End(span, err) }()
+
+	return u.postRepo.CreatePost(ctx, post)
 }
 
-func (u *Usecase) UpdatePost(post models.PostDTO) error {
-	return u.postRepo.UpdatePost(post)
+func (u *Usecase) UpdatePost(ctx context.Context, post models.PostDTO) (err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.UpdatePost")
+	span.SetAttributes(attribute.Int64("post.id", int64(post.ID)))
+	defer func() { tracing.End(span, err) }()
+
+	return u.postRepo.UpdatePost(ctx, post)
 }
 
-func (u *Usecase) DeletePost(id uint64) error {
-	return u.postRepo.DeletePost(id)
+func (u *Usecase) DeletePost(ctx context.Context, id uint64) (err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.DeletePost")
+	span.SetAttributes(attribute.Int64("post.id", int64(id)))
+	defer func() { tracing.End(span, err) }()
+
+	return u.postRepo.DeletePost(ctx, id)
 }
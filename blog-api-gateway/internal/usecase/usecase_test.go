@@ -1,7 +1,10 @@
 package usecase
 
 import (
+	"context"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/mtvy/blog-api-gateway/internal/apperr"
 	"github.com/mtvy/blog-api-gateway/internal/models"
@@ -14,7 +17,7 @@ import (
 
 func newTestRepo(t *testing.T) *repository.PostRepo {
 	repo := repository.NewPostProvider()
-	err := migrations.Migrate(repo)
+	err := migrations.New(repo).Up(context.Background())
 	require.NoError(t, err)
 	return repo
 }
@@ -57,7 +60,7 @@ func TestUsecase_GetPost(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			uc := NewPostProvider(repo)
 
-			post, err := uc.GetPost(tc.id)
+			post, err := uc.GetPost(context.Background(), tc.id)
 			if tc.want.err != nil {
 				require.ErrorContains(t, err, tc.want.err.Error())
 			} else {
@@ -101,13 +104,13 @@ func TestUsecase_CreatePost(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			uc := NewPostProvider(repo)
-			id, err := uc.CreatePost(tc.post)
+			id, err := uc.CreatePost(context.Background(), tc.post)
 			if tc.want.err != nil {
 				require.ErrorContains(t, err, tc.want.err.Error())
 			} else {
 				require.NoError(t, err)
 			}
-			post, err := uc.GetPost(id)
+			post, err := uc.GetPost(context.Background(), id)
 			require.NoError(t, err)
 
 			assert.Equal(t, tc.want.post.Content, post.Content)
@@ -147,7 +150,7 @@ func TestUsecase_DeletePost(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			uc := NewPostProvider(repo)
 
-			err := uc.DeletePost(tc.id)
+			err := uc.DeletePost(context.Background(), tc.id)
 			if tc.want.err != nil {
 				require.ErrorContains(t, err, tc.want.err.Error())
 			} else {
@@ -205,12 +208,12 @@ func TestUsecase_UpdatePost(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			uc := NewPostProvider(repo)
 
-			err := uc.UpdatePost(*tc.post)
+			err := uc.UpdatePost(context.Background(), *tc.post)
 			if tc.want.err != nil {
 				require.ErrorContains(t, err, tc.want.err.Error())
 			} else {
 				require.NoError(t, err)
-				post, err := uc.GetPost(tc.post.ID)
+				post, err := uc.GetPost(context.Background(), tc.post.ID)
 				require.NoError(t, err)
 
 				assert.Equal(t, tc.want.post, post)
@@ -218,3 +221,195 @@ func TestUsecase_UpdatePost(t *testing.T) {
 		})
 	}
 }
+
+func TestUsecase_ContextDone(t *testing.T) {
+	repo := newTestRepo(t)
+	uc := NewPostProvider(repo)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	_, err := uc.GetPost(canceled, 22)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = uc.CreatePost(expired, models.PostDTO{Title: "late"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = uc.DeletePost(expired, 22)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = uc.GetPost(context.Background(), 22)
+	assert.NoError(t, err, "post must survive a request that ran out of time")
+
+	err = migrations.New(repository.NewPostProvider()).Up(canceled)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = NewWebhookProvider(repository.NewWebhookProvider(10)).CreateWebhook(canceled, models.WebhookDTO{URL: "http://localhost"})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = NewAPIKeyProvider(repository.NewAPIKeyProvider()).IssueAPIKey(expired, models.APIKeyDTO{Name: "late"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = NewOutboxProvider(repo).ListOutbox(canceled, "")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// records возвращает next, отдающий posts по одному.
+func records(posts []models.PostRecordDTO) func() (models.PostRecordDTO, error) {
+	return func() (models.PostRecordDTO, error) {
+		if len(posts) == 0 {
+			return models.PostRecordDTO{}, io.EOF
+		}
+		post := posts[0]
+		posts = posts[1:]
+		return post, nil
+	}
+}
+
+func exportPosts(t *testing.T, uc *DatasetUsecase) []models.PostRecordDTO {
+	var posts []models.PostRecordDTO
+	err := uc.Export(context.Background(), func(post models.PostRecordDTO) error {
+		posts = append(posts, post)
+		return nil
+	})
+	require.NoError(t, err)
+	return posts
+}
+
+func TestDatasetUsecase(t *testing.T) {
+	ctx := context.Background()
+	posts := exportPosts(t, NewDatasetProvider(newTestRepo(t)))
+	require.Len(t, posts, 100)
+	for i, post := range posts {
+		assert.Equal(t, uint64(i+1), post.ID)
+	}
+
+	target := repository.NewPostProvider()
+	uc := NewDatasetProvider(target)
+	report, err := uc.Import(ctx, records(posts), models.ImportFail, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportReportDTO{Processed: 100, Created: 100}, report)
+	assert.Equal(t, posts, exportPosts(t, uc))
+
+	created, err := uc.Seed(ctx, records(posts[6:7]))
+	require.NoError(t, err)
+	assert.Equal(t, 1, created)
+	post, err := target.GetPost(ctx, 101)
+	require.NoError(t, err)
+	assert.Equal(t, "Title 7", post.Title, "seeded posts get new ids")
+}
+
+func TestDatasetUsecase_Import(t *testing.T) {
+	type want struct {
+		report models.ImportReportDTO
+		err    error
+		// title - заголовок поста 7 после импорта.
+		title string
+		count int
+	}
+
+	posts := []models.PostRecordDTO{
+		{ID: 7, Title: "Taken", Author: "Author"},
+		{ID: 200, Title: "New", Author: "Author"},
+	}
+
+	testCases := []struct {
+		name string
+		mode models.ImportMode
+		next func() (models.PostRecordDTO, error)
+		want want
+	}{
+		{
+			name: "fail",
+			mode: models.ImportFail,
+			next: records(posts),
+			want: want{
+				err:   apperr.ErrConflict,
+				title: "Title 7",
+				count: 100,
+			},
+		},
+		{
+			name: "skip",
+			mode: models.ImportSkip,
+			next: records(posts),
+			want: want{
+				report: models.ImportReportDTO{Processed: 2, Created: 1, Skipped: 1},
+				title:  "Title 7",
+				count:  101,
+			},
+		},
+		{
+			name: "overwrite",
+			mode: models.ImportOverwrite,
+			next: records(posts),
+			want: want{
+				report: models.ImportReportDTO{Processed: 2, Created: 1, Updated: 1},
+				title:  "Taken",
+				count:  101,
+			},
+		},
+		{
+			name: "remap",
+			mode: models.ImportRemap,
+			next: records(posts),
+			want: want{
+				report: models.ImportReportDTO{
+					Processed: 2, Created: 2,
+					Remapped: map[uint64]uint64{7: 101, 200: 102},
+				},
+				title: "Title 7",
+				count: 102,
+			},
+		},
+		{
+			name: "read_error",
+			mode: models.ImportOverwrite,
+			next: func() (models.PostRecordDTO, error) {
+				return models.PostRecordDTO{}, io.ErrUnexpectedEOF
+			},
+			want: want{
+				err:   io.ErrUnexpectedEOF,
+				title: "Title 7",
+				count: 100,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newTestRepo(t)
+
+			report, err := NewDatasetProvider(repo).Import(ctx, tc.next, tc.mode, nil)
+			if tc.want.err != nil {
+				assert.ErrorIs(t, err, tc.want.err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.want.report, report)
+			assert.Equal(t, tc.want.count, repo.CountPosts())
+
+			post, err := repo.GetPost(ctx, 7)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want.title, post.Title)
+		})
+	}
+}
+
+func TestDatasetUsecase_ImportProgress(t *testing.T) {
+	fixture := exportPosts(t, NewDatasetProvider(newTestRepo(t)))
+	var posts []models.PostRecordDTO
+	for i := 0; i < 25; i++ {
+		posts = append(posts, fixture...)
+	}
+
+	var processed []int
+	uc := NewDatasetProvider(repository.NewPostProvider())
+	report, err := uc.Import(context.Background(), records(posts), models.ImportRemap,
+		func(r models.ImportReportDTO) {
+			processed = append(processed, r.Processed)
+		})
+	require.NoError(t, err)
+	assert.Equal(t, 2500, report.Created)
+	assert.Equal(t, []int{1000, 2000}, processed)
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/pkg/errors"
+)
+
+type webhookProvider interface {
+	ListWebhooks(ctx context.Context) ([]models.WebhookDTO, error)
+	GetWebhook(ctx context.Context, id uint64) (*models.WebhookDTO, error)
+	CreateWebhook(ctx context.Context, webhook models.WebhookDTO) (uint64, error)
+	UpdateWebhook(ctx context.Context, webhook models.WebhookDTO) error
+	DeleteWebhook(ctx context.Context, id uint64) error
+	ListDeliveries(ctx context.Context, webhookID uint64) ([]models.DeliveryDTO, error)
+	ListDeadLetters(ctx context.Context) ([]models.DeadLetterDTO, error)
+}
+
+type WebhookUsecase struct {
+	webhookRepo webhookProvider
+}
+
+func NewWebhookProvider(webhookRepo webhookProvider) *WebhookUsecase {
+	return &WebhookUsecase{
+		webhookRepo: webhookRepo,
+	}
+}
+
+func (u *WebhookUsecase) ListWebhooks(ctx context.Context) ([]models.WebhookDTO, error) {
+	return u.webhookRepo.ListWebhooks(ctx)
+}
+
+func (u *WebhookUsecase) GetWebhook(ctx context.Context, id uint64) (*models.WebhookDTO, error) {
+	return u.webhookRepo.GetWebhook(ctx, id)
+}
+
+// CreateWebhook генерирует секрет подписи, если он не передан.
+func (u *WebhookUsecase) CreateWebhook(ctx context.Context, webhook models.WebhookDTO) (*models.WebhookDTO, error) {
+	if webhook.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return nil, errors.Wrap(err, "generate secret")
+		}
+		webhook.Secret = secret
+	}
+
+	id, err := u.webhookRepo.CreateWebhook(ctx, webhook)
+	if err != nil {
+		return nil, err
+	}
+	return u.webhookRepo.GetWebhook(ctx, id)
+}
+
+func (u *WebhookUsecase) UpdateWebhook(ctx context.Context, webhook models.WebhookDTO) error {
+	return u.webhookRepo.UpdateWebhook(ctx, webhook)
+}
+
+func (u *WebhookUsecase) DeleteWebhook(ctx context.Context, id uint64) error {
+	return u.webhookRepo.DeleteWebhook(ctx, id)
+}
+
+func (u *WebhookUsecase) ListDeliveries(ctx context.Context, webhookID uint64) ([]models.DeliveryDTO, error) {
+	return u.webhookRepo.ListDeliveries(ctx, webhookID)
+}
+
+func (u *WebhookUsecase) ListDeadLetters(ctx context.Context) ([]models.DeadLetterDTO, error) {
+	return u.webhookRepo.ListDeadLetters(ctx)
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,370 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// ErrQueueFull возвращается Send, когда очередь доставок переполнена.
+var ErrQueueFull = errors.New("delivery queue is full")
+
+// errStopped - причина в списке недоставленных для заданий, которые не
+// успели отправить до остановки.
+var errStopped = errors.New("dispatcher stopped before delivery")
+
+type Config struct {
+	Workers        int           `mapstructure:"workers" validate:"gt=0"`
+	QueueSize      int           `mapstructure:"queue_size" validate:"gte=0"`
+	MaxAttempts    int           `mapstructure:"max_attempts" validate:"gt=0"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff" validate:"gte=0"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff" validate:"gtefield=InitialBackoff"`
+	Timeout        time.Duration `mapstructure:"timeout" validate:"gt=0"`
+	LogSize        int           `mapstructure:"log_size" validate:"gte=0"`
+}
+
+type webhookSource interface {
+	ListWebhooks(ctx context.Context) ([]models.WebhookDTO, error)
+}
+
+type deliveryLog interface {
+	AddDelivery(delivery models.DeliveryDTO)
+	AddDeadLetter(letter models.DeadLetterDTO)
+}
+
+type job struct {
+	webhook models.WebhookDTO
+	event   models.PostEvent
+	body    []byte
+	attempt int
+}
+
+// retry - повтор, ожидающий своего времени.
+type retry struct {
+	job   job
+	timer *time.Timer
+}
+
+// Dispatcher доставляет события постов на зарегистрированные вебхуки пулом
+// воркеров. Неудачные доставки повторяются с экспоненциальной задержкой,
+// после MaxAttempts попыток событие попадает в список недоставленных.
+type Dispatcher struct {
+	cfg      Config
+	webhooks webhookSource
+	log      deliveryLog
+	client   *http.Client
+	queue    chan job
+	pending  atomic.Int64
+	done     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	// ctx отменяет отправку, когда срок остановки истек.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// retries - запланированные повторы; повтор забирает из набора либо
+	// таймер, либо Stop.
+	retriesMu sync.Mutex
+	retries   map[*retry]struct{}
+	retryWG   sync.WaitGroup
+}
+
+func New(cfg Config, webhooks webhookSource, log deliveryLog) *Dispatcher {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		cfg:      cfg,
+		webhooks: webhooks,
+		log:      log,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		queue:    make(chan job, cfg.QueueSize),
+		done:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+		retries:  make(map[*retry]struct{}),
+	}
+}
+
+func (d *Dispatcher) Start() {
+	for i := 0; i < d.cfg.Workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+}
+
+// Stop доставляет события из очереди и останавливает воркеров.
+// Запланированные повторы, неудачные при остановке доставки и задания, не
+// отправленные до отмены ctx, попадают в список недоставленных.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.stopOnce.Do(func() {
+		close(d.done)
+	})
+	defer d.cancel()
+
+	d.retriesMu.Lock()
+	for r := range d.retries {
+		r.timer.Stop()
+		delete(d.retries, r)
+		d.drop(r.job, errStopped.Error())
+		d.retryWG.Done()
+	}
+	d.retriesMu.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(stopped)
+	}()
+	var err error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		err = errors.Wrap(ctx.Err(), "deliveries left undelivered")
+		d.cancel()
+		<-stopped
+	}
+	d.retryWG.Wait()
+
+	for {
+		select {
+		case j := <-d.queue:
+			d.drop(j, errStopped.Error())
+		default:
+			return err
+		}
+	}
+}
+
+// Pending возвращает число доставок в очереди и ожидающих повтора.
+func (d *Dispatcher) Pending() int {
+	return int(d.pending.Load())
+}
+
+// Send ставит событие в очередь для всех подписанных вебхуков и не блокирует
+// вызывающего. При переполненной очереди возвращается ErrQueueFull, чтобы
+// outbox повторил событие позже; вебхуки, уже получившие задание, при повторе
+// получат событие еще раз.
+func (d *Dispatcher) Send(event models.PostEvent) error {
+	webhooks, err := d.webhooks.ListWebhooks(context.Background())
+	if err != nil {
+		return errors.Wrap(err, "list webhooks")
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "marshal event")
+	}
+
+	for _, w := range webhooks {
+		if !w.Subscribed(event.Type) {
+			continue
+		}
+		if !d.enqueue(job{webhook: w, event: event, body: body, attempt: 1}) {
+			return errors.Wrapf(ErrQueueFull, "webhook %d", w.ID)
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) enqueue(j job) bool {
+	d.pending.Add(1)
+	select {
+	case d.queue <- j:
+		return true
+	default:
+		d.pending.Add(-1)
+		return false
+	}
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.done:
+			d.flush()
+			return
+		case j := <-d.queue:
+			d.deliver(j)
+		}
+	}
+}
+
+// flush доставляет задания, оставшиеся в очереди к остановке, пока не
+// истек ее срок.
+func (d *Dispatcher) flush() {
+	for {
+		if d.ctx.Err() != nil {
+			return
+		}
+		select {
+		case j := <-d.queue:
+			d.deliver(j)
+		default:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(j job) {
+	start := time.Now()
+	status, err := d.send(j)
+
+	delivery := models.DeliveryDTO{
+		WebhookID:  j.webhook.ID,
+		EventID:    j.event.ID,
+		EventType:  j.event.Type,
+		Attempt:    j.attempt,
+		StatusCode: status,
+		Success:    err == nil,
+		DurationMs: time.Since(start).Milliseconds(),
+		CreatedAt:  start.UTC(),
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	d.log.AddDelivery(delivery)
+
+	if err == nil {
+		d.pending.Add(-1)
+		return
+	}
+
+	if j.attempt >= d.cfg.MaxAttempts {
+		d.drop(j, err.Error())
+		return
+	}
+	d.retry(j, err.Error())
+}
+
+// retry планирует повтор задания. После начала остановки повтор не
+// планируется: задание сразу попадает в список недоставленных.
+func (d *Dispatcher) retry(j job, reason string) {
+	d.retriesMu.Lock()
+	defer d.retriesMu.Unlock()
+
+	select {
+	case <-d.done:
+		d.drop(j, reason)
+		return
+	default:
+	}
+
+	r := &retry{job: j}
+	r.job.attempt++
+	d.retries[r] = struct{}{}
+	d.retryWG.Add(1)
+	r.timer = time.AfterFunc(d.backoff(j.attempt), func() {
+		d.retriesMu.Lock()
+		_, ok := d.retries[r]
+		delete(d.retries, r)
+		d.retriesMu.Unlock()
+		if !ok {
+			// повтор уже забрал Stop
+			return
+		}
+		defer d.retryWG.Done()
+
+		select {
+		case <-d.done:
+			d.drop(r.job, errStopped.Error())
+		case d.queue <- r.job:
+		}
+	})
+}
+
+// drop переносит задание в список недоставленных.
+func (d *Dispatcher) drop(j job, reason string) {
+	d.deadLetter(j, reason)
+	d.pending.Add(-1)
+}
+
+func (d *Dispatcher) send(j job) (int, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, j.webhook.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, errors.Wrap(err, "new request")
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(j.event.Type))
+	req.Header.Set(HeaderDelivery, j.event.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(j.webhook.Secret, timestamp, j.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "send request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff возвращает задержку перед повтором с номером attempt (начиная с 1)
+// с равномерным джиттером до 20%.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.InitialBackoff << (attempt - 1)
+	if delay <= 0 || (d.cfg.MaxBackoff > 0 && delay > d.cfg.MaxBackoff) {
+		delay = d.cfg.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay + rand.N(delay/5+1)
+}
+
+func (d *Dispatcher) deadLetter(j job, reason string) {
+	slog.Warn("webhook delivery failed",
+		slog.Uint64("webhook_id", j.webhook.ID),
+		slog.String("event_id", j.event.ID),
+		slog.String("error", reason))
+
+	d.log.AddDeadLetter(models.DeadLetterDTO{
+		WebhookID: j.webhook.ID,
+		Event:     j.event,
+		Attempts:  j.attempt,
+		LastError: reason,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// Sign возвращает подпись тела запроса в формате "sha256=<hex>".
+// Подписывается строка "<timestamp>.<body>".
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/internal/repository"
+	"github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef"
+
+func newTestDispatcher(t *testing.T, url string, events ...models.PostEventType) (*Dispatcher, *repository.WebhookRepo, uint64) {
+	repo := repository.NewWebhookProvider(10)
+	id, err := repo.CreateWebhook(context.Background(), models.WebhookDTO{URL: url, Secret: testSecret, Events: events, Active: true})
+	require.NoError(t, err)
+
+	d := New(Config{
+		Workers:        2,
+		QueueSize:      10,
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		Timeout:        time.Second,
+	}, repo, repo)
+	d.Start()
+	t.Cleanup(func() { _ = d.Stop(context.Background()) })
+	return d, repo, id
+}
+
+func waitPending(t *testing.T, d *Dispatcher) {
+	require.Eventually(t, func() bool { return d.Pending() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestDispatcher_SignedDelivery(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	d, repo, id := newTestDispatcher(t, srv.URL)
+	event := models.NewPostEvent(models.PostCreated, models.PostDTO{ID: 1, Title: "Title"})
+	require.NoError(t, d.Send(event))
+
+	req := <-received
+	body := <-bodies
+	assert.Equal(t, string(models.PostCreated), req.Header.Get(HeaderEvent))
+	assert.Equal(t, event.ID, req.Header.Get(HeaderDelivery))
+	assert.Equal(t, Sign(testSecret, req.Header.Get(HeaderTimestamp), body), req.Header.Get(HeaderSignature))
+
+	waitPending(t, d)
+	deliveries, err := repo.ListDeliveries(context.Background(), id)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.True(t, deliveries[0].Success)
+}
+
+func TestDispatcher_Retry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	d, repo, id := newTestDispatcher(t, srv.URL)
+	require.NoError(t, d.Send(models.NewPostEvent(models.PostUpdated, models.PostDTO{ID: 1})))
+	waitPending(t, d)
+
+	deliveries, err := repo.ListDeliveries(context.Background(), id)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 3)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[0].StatusCode)
+	assert.True(t, deliveries[2].Success)
+
+	letters, err := repo.ListDeadLetters(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, letters)
+}
+
+func TestDispatcher_DeadLetter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	d, repo, _ := newTestDispatcher(t, srv.URL)
+	event := models.NewPostEvent(models.PostDeleted, models.PostDTO{ID: 1})
+	require.NoError(t, d.Send(event))
+	waitPending(t, d)
+
+	letters, err := repo.ListDeadLetters(context.Background())
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, event.ID, letters[0].Event.ID)
+	assert.Equal(t, 3, letters[0].Attempts)
+}
+
+func TestDispatcher_EventFilter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	d, _, _ := newTestDispatcher(t, srv.URL, models.PostDeleted)
+	require.NoError(t, d.Send(models.NewPostEvent(models.PostCreated, models.PostDTO{ID: 1})))
+	require.NoError(t, d.Send(models.NewPostEvent(models.PostDeleted, models.PostDTO{ID: 1})))
+	waitPending(t, d)
+
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestDispatcher_StopFlushesQueue(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	repo := repository.NewWebhookProvider(10)
+	_, err := repo.CreateWebhook(context.Background(), models.WebhookDTO{URL: srv.URL, Secret: testSecret, Active: true})
+	require.NoError(t, err)
+	d := New(Config{Workers: 1, QueueSize: 10, MaxAttempts: 1, Timeout: time.Second}, repo, repo)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, d.Send(models.NewPostEvent(models.PostCreated, models.PostDTO{ID: uint64(i)})))
+	}
+	d.Start()
+	require.NoError(t, d.Stop(context.Background()))
+
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Zero(t, d.Pending())
+}
+
+func TestDispatcher_StopDeadLettersRetries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	repo := repository.NewWebhookProvider(10)
+	_, err := repo.CreateWebhook(context.Background(), models.WebhookDTO{URL: srv.URL, Secret: testSecret, Active: true})
+	require.NoError(t, err)
+	d := New(Config{Workers: 1, QueueSize: 10, MaxAttempts: 5, InitialBackoff: time.Hour, MaxBackoff: time.Hour, Timeout: time.Second}, repo, repo)
+	d.Start()
+
+	event := models.NewPostEvent(models.PostCreated, models.PostDTO{ID: 1})
+	require.NoError(t, d.Send(event))
+	require.Eventually(t, func() bool {
+		deliveries, _ := repo.ListDeliveries(context.Background(), 1)
+		return len(deliveries) == 1
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, d.Stop(context.Background()))
+	assert.Zero(t, d.Pending())
+
+	letters, err := repo.ListDeadLetters(context.Background())
+	require.NoError(t, err)
+	require.Len(t, letters, 1, "a retry scheduled at shutdown is not lost")
+	assert.Equal(t, event.ID, letters[0].Event.ID)
+	assert.Equal(t, errStopped.Error(), letters[0].LastError)
+}
+
+func TestDispatcher_StopDeadline(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	repo := repository.NewWebhookProvider(10)
+	_, err := repo.CreateWebhook(context.Background(), models.WebhookDTO{URL: srv.URL, Secret: testSecret, Active: true})
+	require.NoError(t, err)
+	d := New(Config{Workers: 1, QueueSize: 10, MaxAttempts: 3, Timeout: time.Minute}, repo, repo)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, d.Send(models.NewPostEvent(models.PostCreated, models.PostDTO{ID: uint64(i)})))
+	}
+	d.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = d.Stop(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Zero(t, d.Pending())
+
+	letters, err := repo.ListDeadLetters(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, letters, 3, "unsent deliveries are dead-lettered")
+}
+
+func TestDispatcher_QueueFull(t *testing.T) {
+	repo := repository.NewWebhookProvider(10)
+	_, err := repo.CreateWebhook(context.Background(), models.WebhookDTO{URL: "http://127.0.0.1:1", Secret: testSecret, Active: true})
+	require.NoError(t, err)
+	d := New(Config{Workers: 1, QueueSize: 1, MaxAttempts: 1, Timeout: time.Second}, repo, repo)
+
+	require.NoError(t, d.Send(models.NewPostEvent(models.PostCreated, models.PostDTO{ID: 1})))
+	err = d.Send(models.NewPostEvent(models.PostCreated, models.PostDTO{ID: 2}))
+	assert.True(t, errors.Is(err, ErrQueueFull))
+	assert.Equal(t, 1, d.Pending())
+
+	letters, err := repo.ListDeadLetters(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, letters, "queue overflow is retried by the outbox, not dead-lettered")
+}
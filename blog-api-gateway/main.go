@@ -4,12 +4,12 @@ import (
 	"log/slog"
 	"os"
 
-	"github.com/mtvy/blog-api-gateway/internal/app"
+	"github.com/mtvy/blog-api-gateway/internal/cli"
 )
 
 func main() {
-	if err := app.Run(); err != nil {
-		slog.Error("app run", slog.Any("error", err))
+	if err := cli.Run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		slog.Error("app run", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 }
@@ -1,35 +1,207 @@
 package migrations
 
 import (
+	"context"
 	_ "embed"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"time"
 
+	"github.com/mtvy/blog-api-gateway/internal/apperr"
 	"github.com/mtvy/blog-api-gateway/internal/models"
 	"github.com/pkg/errors"
 )
 
-type postsProvider interface {
-	CreatePost(post models.PostDTO) (uint64, error)
+var ErrNoApplied = errors.New("no applied migrations")
+
+// Store - хранилище, к которому применяются миграции. Посты создаются и
+// удаляются обычным путем, с событиями в outbox; примененные миграции
+// хранятся там же.
+type Store interface {
+	CreatePost(ctx context.Context, post models.PostDTO) (uint64, error)
+	DeletePost(ctx context.Context, id uint64) error
+	ListMigrations(ctx context.Context) ([]models.MigrationDTO, error)
+	SaveMigration(ctx context.Context, migration models.MigrationDTO) error
+	DeleteMigration(ctx context.Context, version int) error
+}
+
+// Migration изменяет данные хранилища. Up возвращает идентификаторы
+// созданных постов, Down получает их обратно и отменяет изменения Up.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, s Store) ([]uint64, error)
+	Down    func(ctx context.Context, s Store, postIDs []uint64) error
+}
+
+type Status struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
 }
 
 //go:embed blog_data.json
 var blogData []byte
 
-type postsDTO struct {
-	Posts []models.PostDTO `json:"posts"`
+// all - миграции в порядке применения.
+var all = []Migration{
+	{
+		Version: 1,
+		Name:    "initial_posts",
+		Up: func(ctx context.Context, s Store) ([]uint64, error) {
+			posts, err := initialPosts()
+			if err != nil {
+				return nil, err
+			}
+			ids, err := Seed(ctx, s, posts)
+			if err != nil {
+				// непримененная миграция не откатывается, поэтому созданные
+				// посты удаляются сразу
+				if rollbackErr := unseed(context.WithoutCancel(ctx), s, ids); rollbackErr != nil {
+					return nil, stderrors.Join(err, rollbackErr)
+				}
+				return nil, err
+			}
+			return ids, nil
+		},
+		Down: unseed,
+	},
+}
+
+// Migrator применяет миграции по порядку и откатывает их по одной.
+// Примененные миграции хранятся в Store, поэтому их видит и новый Migrator
+// того же хранилища.
+type Migrator struct {
+	store      Store
+	migrations []Migration
+
+	// mu не дает применять и откатывать миграции одновременно
+	mu sync.Mutex
+}
+
+func New(store Store) *Migrator {
+	return &Migrator{
+		store:      store,
+		migrations: all,
+	}
+}
+
+// Up применяет все непримененные миграции. Отмена ctx прерывает применение;
+// уже примененные миграции остаются.
+func (m *Migrator) Up(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		ids, err := migration.Up(ctx, m.store)
+		if err != nil {
+			return errors.Wrapf(err, "migration %d %s", migration.Version, migration.Name)
+		}
+		// изменения уже внесены, поэтому отмена ctx не должна помешать их учесть
+		record := models.MigrationDTO{Version: migration.Version, PostIDs: ids, AppliedAt: time.Now().UTC()}
+		if err := m.store.SaveMigration(context.WithoutCancel(ctx), record); err != nil {
+			return errors.Wrapf(err, "save migration %d %s", migration.Version, migration.Name)
+		}
+		slog.Info("migration applied", slog.Int("version", migration.Version), slog.String("name", migration.Name))
+	}
+	return nil
+}
+
+// Down откатывает последнюю примененную миграцию.
+func (m *Migrator) Down(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		record, ok := applied[migration.Version]
+		if !ok {
+			continue
+		}
+		if err := migration.Down(ctx, m.store, record.PostIDs); err != nil {
+			return errors.Wrapf(err, "rollback migration %d %s", migration.Version, migration.Name)
+		}
+		if err := m.store.DeleteMigration(context.WithoutCancel(ctx), migration.Version); err != nil {
+			return errors.Wrapf(err, "delete migration %d %s", migration.Version, migration.Name)
+		}
+		slog.Info("migration rolled back", slog.Int("version", migration.Version), slog.String("name", migration.Name))
+		return nil
+	}
+	return ErrNoApplied
 }
 
-func Migrate(postsRepo postsProvider) error {
-	posts := postsDTO{}
-	if err := json.Unmarshal(blogData, &posts); err != nil {
-		return errors.Wrap(err, "unmarshal blog data")
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		_, ok := applied[migration.Version]
+		statuses = append(statuses, Status{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Applied: ok,
+		})
 	}
-	for _, post := range posts.Posts {
-		if _, err := postsRepo.CreatePost(post); err != nil {
-			return errors.Wrap(err, fmt.Sprintf("create post %s", post.Title))
+	return statuses, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]models.MigrationDTO, error) {
+	records, err := m.store.ListMigrations(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "list migrations")
+	}
+	applied := make(map[int]models.MigrationDTO, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// Seed добавляет посты с новыми идентификаторами и возвращает их, при ошибке -
+// идентификаторы постов, созданных до нее.
+func Seed(ctx context.Context, s Store, posts []models.PostRecordDTO) ([]uint64, error) {
+	ids := make([]uint64, 0, len(posts))
+	for _, post := range posts {
+		id, err := s.CreatePost(ctx, post.ToDTO())
+		if err != nil {
+			return ids, errors.Wrap(err, fmt.Sprintf("create post %s", post.Title))
 		}
+		ids = append(ids, id)
 	}
+	return ids, nil
+}
 
+// unseed удаляет посты ids; уже удаленные пропускаются.
+func unseed(ctx context.Context, s Store, ids []uint64) error {
+	for _, id := range ids {
+		if err := s.DeletePost(ctx, id); err != nil && !errors.Is(err, apperr.ErrNotFound) {
+			return errors.Wrapf(err, "delete post %d", id)
+		}
+	}
 	return nil
 }
+
+func initialPosts() ([]models.PostRecordDTO, error) {
+	var data models.DatasetDTO
+	if err := json.Unmarshal(blogData, &data); err != nil {
+		return nil, errors.Wrap(err, "unmarshal blog data")
+	}
+	return data.Posts, nil
+}
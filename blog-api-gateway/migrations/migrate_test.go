@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mtvy/blog-api-gateway/internal/models"
+	"github.com/mtvy/blog-api-gateway/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewPostProvider()
+	migrator := New(repo)
+
+	err := migrator.Down(ctx)
+	assert.ErrorIs(t, err, ErrNoApplied)
+
+	require.NoError(t, migrator.Up(ctx))
+	require.NoError(t, New(repo).Up(ctx), "applied migrations are kept in the store and skipped")
+	assert.Equal(t, 100, repo.CountPosts())
+	assert.Equal(t, 100, repo.CountOutbox()[models.OutboxPending], "seeded posts produce post events")
+
+	statuses, err := New(repo).Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Status{{Version: 1, Name: "initial_posts", Applied: true}}, statuses)
+
+	id, err := repo.CreatePost(ctx, models.PostDTO{Title: "Title 1", Author: "Author 1"})
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdatePost(ctx, models.PostDTO{ID: 2, Title: "Edited", Author: "Author 2"}))
+	require.NoError(t, repo.DeletePost(ctx, 3))
+
+	require.NoError(t, migrator.Down(ctx))
+	assert.Equal(t, 1, repo.CountPosts(), "only posts created by the migration are removed")
+	_, err = repo.GetPost(ctx, id)
+	assert.NoError(t, err, "a post equal to a seeded one is kept")
+
+	statuses, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, statuses[0].Applied)
+}